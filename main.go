@@ -19,31 +19,94 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"os/signal"
 	"runtime"
 	"runtime/pprof"
+	"strings"
+	"syscall"
+	"time"
 
 	ripley "github.com/loveholidays/ripley/pkg"
 )
 
+const (
+	exitCodeUsage        = 2
+	exitCodeSLOViolation = 3
+	exitCodeAborted      = 4
+	exitCodeInvalidInput = 126
+	exitCodeInterrupted  = 130
+)
+
 func main() {
-	exitCode := 0
+	os.Exit(run())
+}
+
+// run is separate from main so that deferred functions run before exiting
+func run() int {
+	config := ripley.DefaultConfig()
 
-	paceStr := flag.String("pace", "10s@1", `[duration]@[rate], e.g. "1m@1 30s@1.5 1h@2"`)
+	flag.StringVar(&config.Pace, "pace", config.Pace, `[duration]@[rate], e.g. "1m@1 30s@1.5 1h@2", or ramps from one rate to another with [duration]@[rate]->[rate] (linear) or [duration]@[rate]~exp~[rate] (exponential), e.g. "5m@1->10 10m@10~exp~100"`)
 	silent := flag.Bool("silent", false, "Suppress output")
-	dryRun := flag.Bool("dry-run", false, "Consume input but do not send HTTP requests to targets")
-	timeout := flag.Int("timeout", 10, "HTTP client timeout in seconds")
-	connections := flag.Int("connections", 10000, "Max open idle connections per target host")
-	maxConnections := flag.Int("max-connections", 0, "Max connections per target host (default unlimited)")
-	disableKeepAlives := flag.Bool("disable-keepalives", false, "Disable HTTP keep-alives (forces new connection per request)")
-	strict := flag.Bool("strict", false, "Panic on bad input")
+	flag.BoolVar(&config.DryRun, "dry-run", config.DryRun, "Consume input but do not send HTTP requests to targets")
+	timeout := flag.Int("timeout", int(config.Timeout.Seconds()), "HTTP client timeout in seconds")
+	flag.IntVar(&config.Connections, "connections", config.Connections, "Max open idle connections per target host")
+	flag.IntVar(&config.MaxConnections, "max-connections", config.MaxConnections, "Max connections per target host (default unlimited)")
+	flag.BoolVar(&config.DisableKeepAlives, "disable-keepalives", config.DisableKeepAlives, "Disable HTTP keep-alives (forces new connection per request)")
+	flag.BoolVar(&config.Strict, "strict", config.Strict, "Stop on bad input")
 	memprofile := flag.String("memprofile", "", "Write memory profile to `file` before exit")
 	cpuprofile := flag.String("cpuprofile", "", "Write cpu profile to `file` before exit")
-	numWorkers := flag.Int("workers", runtime.NumCPU()*2, "Number of client workers to use")
-	metricsServerEnable := flag.Bool("metricsServerEnable", false, "Enable Prometheus metrics server on /metrics endpoint")
-	metricsServerAddr := flag.String("metricsServerAddr", "0.0.0.0:8081", "Metrics server listen address")
-	printStatsInterval := flag.Duration("print-stats", 0, `Statistics report interval, e.g., "1m"
+	flag.IntVar(&config.Workers, "workers", config.Workers, "Number of client workers to use")
+	flag.IntVar(&config.Autoscale.MinWorkers, "min-workers", config.Autoscale.MinWorkers, `Fewest workers the pool shrinks to when "max-workers" is set (default 1)`)
+	flag.IntVar(&config.Autoscale.MaxWorkers, "max-workers", config.Autoscale.MaxWorkers, "Grow the worker pool up to this many workers when requests are sent late or all workers are busy, and shrink it when most are idle (default fixed size)")
+	flag.DurationVar(&config.Autoscale.Interval, "autoscale-interval", config.Autoscale.Interval, "How often the worker pool is resized")
+	flag.DurationVar(&config.Autoscale.MaxSkew, "autoscale-max-skew", config.Autoscale.MaxSkew, "The worker pool grows when requests are sent later than this after their scheduled time on average")
+	flag.IntVar(&config.MaxInFlight, "max-in-flight", config.MaxInFlight, "Start workers on demand so the pacer never waits for busy workers, up to this many requests in flight (default closed model with a fixed number of workers)")
+	flag.BoolVar(&config.DropWhenFull, "drop-when-full", config.DropWhenFull, `Skip requests when "max-in-flight" requests are in flight instead of waiting`)
+	flag.DurationVar(&config.DrainTimeout, "drain-timeout", config.DrainTimeout, "How long to wait for in-flight requests when interrupted or aborted, 0 waits up to the HTTP client timeout")
+	flag.BoolVar(&config.Metrics.Enabled, "metricsServerEnable", config.Metrics.Enabled, "Enable Prometheus metrics server on /metrics endpoint")
+	flag.StringVar(&config.Metrics.Address, "metricsServerAddr", config.Metrics.Address, "Metrics server listen address")
+	summaryFormat := flag.String("summary", "", `Print an end of run summary to stderr in "text" or "json" format`)
+	flag.StringVar(&config.SLOs, "slo", config.SLOs, `[scope.]metric[op]threshold, e.g. "p99<300ms error_rate<0.5% phase[2].status_5xx<10 host[localhost:8080].p95<1s"
+
+Exits with code 3 when any of the SLOs is violated at the end of the run.`)
+	flag.StringVar(&config.CircuitBreaker.Conditions, "abort", config.CircuitBreaker.Conditions, `Abort the run when any condition holds over the abort window, e.g. "error_rate>50% status_5xx_rate>20% p95>2s"
+
+Conditions use the same metrics as the "slo" flag but cannot be scoped. Exits with code 4 when aborted.`)
+	flag.DurationVar(&config.CircuitBreaker.Window, "abort-window", config.CircuitBreaker.Window, "Sliding window over which abort conditions are evaluated")
+	flag.DurationVar(&config.CircuitBreaker.For, "abort-for", config.CircuitBreaker.For, "How long an abort condition must hold before the run is aborted")
+	flag.IntVar(&config.CircuitBreaker.MinRequests, "abort-min-requests", config.CircuitBreaker.MinRequests, "Minimum number of results in the abort window before abort conditions are evaluated")
+	assertionsFile := flag.String("assertions", "", "Check every response against the assertions in this JSON `file`, e.g. {\"status\": [200], \"bodyContains\": \"ok\"}\n\nRequests with assertions of their own are checked against those instead.")
+	filterMethods := flag.String("filter-method", "", "Comma separated methods of the requests replayed, e.g. GET,HEAD")
+	flag.StringVar(&config.Filter.Host, "filter-host", config.Filter.Host, "Replay only requests whose URL host matches this regular expression")
+	flag.StringVar(&config.Filter.URL, "filter-url", config.Filter.URL, "Replay only requests whose URL matches this regular expression")
+	var filterHeaders, dropPatterns repeatedFlag
+	flag.Var(&filterHeaders, "filter-header", `Replay only requests with a header value matching a regular expression, e.g. "X-Tenant=^acme$", can be repeated`)
+	flag.Var(&dropPatterns, "drop", `Skip requests whose URL matches this regular expression, e.g. "/health", can be repeated`)
+	flag.Float64Var(&config.Filter.SamplePercent, "sample", config.Filter.SamplePercent, "Replay only this percentage of requests, selected by a hash of the URL so every run replays the same URLs")
+	flag.BoolVar(&config.Templates, "templates", config.Templates, `Render the URL, header values and body of requests as templates before sending them, e.g. "/users/{{randInt 1 100}}?id={{uuid}}"`)
+	rulesFile := flag.String("rules", "", "Rewrite every request with the rules in this YAML or JSON `file` before replaying it")
+	flag.StringVar(&config.FailedBodiesDir, "save-failed-bodies", config.FailedBodiesDir, "Save the bodies of responses failing their assertions to this `directory`")
+	flag.StringVar(&config.Shadow.Baseline, "shadow-baseline", config.Shadow.Baseline, `Send every request to this baseline target as well as to "shadow-candidate" and compare the responses, e.g. "http://old-service:8080"`)
+	flag.StringVar(&config.Shadow.Candidate, "shadow-candidate", config.Shadow.Candidate, `Candidate target compared against "shadow-baseline", e.g. "http://new-service:8080"`)
+	shadowHeaders := flag.String("shadow-headers", "", "Comma separated response headers compared in shadow mode besides the status code and body")
+	shadowIgnoreFields := flag.String("shadow-ignore-fields", "", "Comma separated JSON body keys ignored in shadow mode, e.g. timestamps and request IDs")
+	authTokenURL := flag.String("auth-token-url", "", "Obtain a token with the OAuth2 client credentials grant from this token endpoint and send it in every request")
+	authClientID := flag.String("auth-client-id", os.Getenv("RIPLEY_AUTH_CLIENT_ID"), "OAuth2 client ID, defaults to $RIPLEY_AUTH_CLIENT_ID")
+	authClientSecret := flag.String("auth-client-secret", os.Getenv("RIPLEY_AUTH_CLIENT_SECRET"), "OAuth2 client secret, defaults to $RIPLEY_AUTH_CLIENT_SECRET")
+	authScopes := flag.String("auth-scopes", "", "Comma separated OAuth2 scopes")
+	authTokenFile := flag.String("auth-token-file", "", "Send the token in this `file` in every request, either the bare token or a JSON token response")
+	authCommand := flag.String("auth-command", "", "Send the token printed by this shell command in every request, either the bare token or a JSON token response")
+	flag.StringVar(&config.Auth.Header, "auth-header", config.Auth.Header, `Header the token is sent in (default "Authorization")`)
+	flag.StringVar(&config.Auth.Format, "auth-format", config.Auth.Format, `Header value with %s replaced by the token (default "Bearer %s" for the Authorization header)`)
+	flag.StringVar(&config.Auth.Cookie, "auth-cookie", config.Auth.Cookie, "Send the token in this cookie instead of a header")
+	flag.DurationVar(&config.Auth.RefreshBefore, "auth-refresh-before", config.Auth.RefreshBefore, "Refresh tokens this long before they expire")
+	flag.DurationVar(&config.Auth.RefreshInterval, "auth-refresh-interval", config.Auth.RefreshInterval, "Refresh tokens without an expiry at this interval, never when 0")
+	flag.DurationVar(&config.StatsInterval, "print-stats", config.StatsInterval, `Statistics report interval, e.g., "1m"
 
 Each report line is printed to stderr with the following fields in logfmt format:
 
@@ -71,6 +134,48 @@ When 0 (default) or negative, reporting is switched off.
   `)
 
 	flag.Parse()
+	config.Timeout = time.Duration(*timeout) * time.Second
+	config.Shadow.Headers = splitList(*shadowHeaders)
+	config.Shadow.IgnoreFields = splitList(*shadowIgnoreFields)
+	config.Filter.Methods = splitList(*filterMethods)
+	config.Filter.Drop = dropPatterns
+
+	tokenSource, err := newTokenSource(*authTokenURL, *authClientID, *authClientSecret, splitList(*authScopes), *authTokenFile, *authCommand)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeUsage
+	}
+	config.Auth.Source = tokenSource
+
+	for _, header := range filterHeaders {
+		name, pattern, ok := strings.Cut(header, "=")
+		if !ok || name == "" {
+			fmt.Fprintf(os.Stderr, "invalid header filter %q, want name=regexp\n", header)
+			return exitCodeUsage
+		}
+		if config.Filter.Headers == nil {
+			config.Filter.Headers = make(map[string]string)
+		}
+		config.Filter.Headers[name] = pattern
+	}
+
+	if *assertionsFile != "" {
+		assertions, err := ripley.LoadAssertions(*assertionsFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeUsage
+		}
+		config.Assertions = assertions
+	}
+
+	if *rulesFile != "" {
+		rules, err := ripley.LoadRules(*rulesFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeUsage
+		}
+		config.Rules = rules
+	}
 
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)
@@ -92,7 +197,7 @@ When 0 (default) or negative, reporting is switched off.
 		defer pprof.StopCPUProfile()
 	}
 
-	exitCode = ripley.Replay(*paceStr, *silent, *dryRun, *timeout, *strict, *numWorkers, *connections, *maxConnections, *disableKeepAlives, *printStatsInterval, *metricsServerEnable, *metricsServerAddr)
+	exitCode := replay(config, *silent, *summaryFormat)
 
 	if *memprofile != "" {
 		f, err := os.Create(*memprofile)
@@ -113,5 +218,129 @@ When 0 (default) or negative, reporting is switched off.
 		}
 	}
 
-	os.Exit(exitCode)
+	return exitCode
+}
+
+// replay runs ripley over STDIN and maps the outcome of the run to an exit code
+func replay(config ripley.Config, silent bool, summaryFormat string) int {
+	if summaryFormat != "" && summaryFormat != "text" && summaryFormat != "json" {
+		fmt.Fprintf(os.Stderr, "unknown summary format: %s\n", summaryFormat)
+		return exitCodeUsage
+	}
+
+	replayer, err := ripley.New(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeUsage
+	}
+
+	sink := ripley.DiscardResults
+	if !silent {
+		sink = ripley.NewJSONLSink(os.Stdout)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Stop sending requests on the first SIGINT or SIGTERM and let in-flight
+	// requests drain. A second signal terminates ripley immediately.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			fmt.Fprintf(os.Stderr, "received %s, draining in-flight requests\n", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	summary, err := replayer.Run(ctx, ripley.NewJSONLSource(os.Stdin), sink)
+
+	if summary != nil && summaryFormat != "" {
+		if err := summary.Write(os.Stderr, summaryFormat); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	return exitCodeOf(err)
+}
+
+func exitCodeOf(err error) int {
+	var aborted *ripley.AbortedError
+	var sloViolation *ripley.SLOViolationError
+	var invalidRequest *ripley.InvalidRequestError
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &aborted):
+		return exitCodeAborted
+	case errors.Is(err, context.Canceled):
+		return exitCodeInterrupted
+	case errors.As(err, &sloViolation):
+		return exitCodeSLOViolation
+	case errors.Is(err, ripley.ErrInvalidInput), errors.As(err, &invalidRequest):
+		return exitCodeInvalidInput
+	default:
+		return 1
+	}
+}
+
+// splitList splits a comma separated flag value, ignoring empty items
+func splitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// repeatedFlag collects the values of a flag given several times
+type repeatedFlag []string
+
+func (f *repeatedFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *repeatedFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// newTokenSource returns the token source selected by the auth flags, nil when none is
+func newTokenSource(tokenURL, clientID, clientSecret string, scopes []string, tokenFile, command string) (ripley.TokenSource, error) {
+	var sources []ripley.TokenSource
+
+	if tokenURL != "" {
+		sources = append(sources, &ripley.ClientCredentials{TokenURL: tokenURL, ClientID: clientID, ClientSecret: clientSecret, Scopes: scopes})
+	}
+
+	if tokenFile != "" {
+		sources = append(sources, &ripley.FileToken{Path: tokenFile})
+	}
+
+	if command != "" {
+		sources = append(sources, &ripley.CommandToken{Command: command})
+	}
+
+	switch len(sources) {
+	case 0:
+		return nil, nil
+	case 1:
+		return sources[0], nil
+	default:
+		return nil, errors.New("only one of auth-token-url, auth-token-file and auth-command can be given")
+	}
 }
@@ -0,0 +1,274 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Bodies of responses failing their assertions are saved up to this size
+const maxSavedBodyLength = 1 << 20
+
+// Assertions are checked against every response. A response failing any of
+// them is reported as an assertion failure, which is not a transport error.
+type Assertions struct {
+	// The status code must be one of these
+	Status []int `json:"status,omitempty"`
+	// Headers that must be present in the response
+	Headers []string `json:"headers,omitempty"`
+	// The body must contain this string
+	BodyContains string `json:"bodyContains,omitempty"`
+	// The body must match this regular expression
+	BodyMatches string `json:"bodyMatches,omitempty"`
+	// The body must be JSON with these values at these paths, e.g. {"$.items[0].id": 1}
+	JSONPath map[string]any `json:"jsonPath,omitempty"`
+
+	bodyPattern *regexp.Regexp
+	jsonPaths   map[string][]pathSegment
+	compiled    bool
+}
+
+// LoadAssertions reads assertions in JSON format from a file
+func LoadAssertions(path string) (*Assertions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	assertions := &Assertions{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(assertions); err != nil {
+		return nil, fmt.Errorf("invalid assertions in %s: %w", path, err)
+	}
+
+	if err := assertions.compile(); err != nil {
+		return nil, fmt.Errorf("invalid assertions in %s: %w", path, err)
+	}
+
+	return assertions, nil
+}
+
+func (a *Assertions) compile() error {
+	if a.BodyMatches != "" {
+		pattern, err := regexp.Compile(a.BodyMatches)
+		if err != nil {
+			return fmt.Errorf("invalid bodyMatches: %w", err)
+		}
+		a.bodyPattern = pattern
+	}
+
+	a.jsonPaths = make(map[string][]pathSegment, len(a.JSONPath))
+	for path, expected := range a.JSONPath {
+		segments, err := parseJSONPath(path)
+		if err != nil {
+			return err
+		}
+		a.jsonPaths[path] = segments
+
+		// Compare against values as decoded from JSON, e.g. float64 rather than int
+		data, err := json.Marshal(expected)
+		if err != nil {
+			return fmt.Errorf("invalid value for JSON path %s: %w", path, err)
+		}
+		if err := json.Unmarshal(data, &expected); err != nil {
+			return fmt.Errorf("invalid value for JSON path %s: %w", path, err)
+		}
+		a.JSONPath[path] = expected
+	}
+
+	for _, name := range a.Headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("invalid header name: %q", name)
+		}
+	}
+
+	a.compiled = true
+	return nil
+}
+
+// check returns the first assertion the response fails
+func (a *Assertions) check(resp *http.Response, body []byte) error {
+	if len(a.Status) > 0 && !slices.Contains(a.Status, resp.StatusCode) {
+		return fmt.Errorf("status %d not in %v", resp.StatusCode, a.Status)
+	}
+
+	for _, name := range a.Headers {
+		if len(resp.Header.Values(name)) == 0 {
+			return fmt.Errorf("header %s missing", name)
+		}
+	}
+
+	if a.BodyContains != "" && !bytes.Contains(body, []byte(a.BodyContains)) {
+		return fmt.Errorf("body does not contain %q", a.BodyContains)
+	}
+
+	if a.bodyPattern != nil && !a.bodyPattern.Match(body) {
+		return fmt.Errorf("body does not match %q", a.BodyMatches)
+	}
+
+	if len(a.JSONPath) == 0 {
+		return nil
+	}
+
+	var document any
+	if err := json.Unmarshal(body, &document); err != nil {
+		return fmt.Errorf("body is not JSON: %w", err)
+	}
+
+	for _, path := range sortedKeys(a.JSONPath) {
+		expected := a.JSONPath[path]
+		actual, ok := lookupJSONPath(document, a.jsonPaths[path])
+
+		if !ok {
+			return fmt.Errorf("%s not found", path)
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			return fmt.Errorf("%s is %s, want %s", path, jsonString(actual), jsonString(expected))
+		}
+	}
+
+	return nil
+}
+
+func jsonString(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// pathSegment is an object key, which may be empty, or an array index
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+var jsonPathSegmentPattern = regexp.MustCompile(`^(?:\.([A-Za-z_][A-Za-z0-9_-]*)|\[(\d+)\]|\["([^"]*)"\])`)
+
+// parseJSONPath supports the subset of JSONPath selecting a single value,
+// e.g. $.items[0].id or $["content-type"]
+func parseJSONPath(path string) ([]pathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid JSON path %s: must start with $", path)
+	}
+
+	var segments []pathSegment
+
+	for rest := path[1:]; rest != ""; {
+		tokens := jsonPathSegmentPattern.FindStringSubmatch(rest)
+		if tokens == nil {
+			return nil, fmt.Errorf("invalid JSON path %s at %s", path, rest)
+		}
+
+		switch {
+		case tokens[2] != "":
+			index, err := strconv.Atoi(tokens[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON path %s: %w", path, err)
+			}
+			segments = append(segments, pathSegment{index: index, isIndex: true})
+		case tokens[1] != "":
+			segments = append(segments, pathSegment{key: tokens[1]})
+		default:
+			segments = append(segments, pathSegment{key: tokens[3]})
+		}
+
+		rest = rest[len(tokens[0]):]
+	}
+
+	return segments, nil
+}
+
+func lookupJSONPath(document any, segments []pathSegment) (any, bool) {
+	for _, segment := range segments {
+		if !segment.isIndex {
+			object, ok := document.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if document, ok = object[segment.key]; !ok {
+				return nil, false
+			}
+			continue
+		}
+
+		array, ok := document.([]any)
+		if !ok || segment.index >= len(array) {
+			return nil, false
+		}
+		document = array[segment.index]
+	}
+
+	return document, true
+}
+
+// responseChecker checks responses against the request's assertions, or the
+// global assertions for requests without any, and saves the bodies of
+// responses failing them. It is shared by all workers.
+type responseChecker struct {
+	assertions *Assertions
+	bodiesDir  string
+	saved      atomic.Int64
+}
+
+func (c *responseChecker) check(req *Request, resp *http.Response, body []byte, result *Result) {
+	assertions := req.Assertions
+	if assertions == nil {
+		assertions = c.assertions
+	}
+
+	if assertions == nil {
+		return
+	}
+
+	err := assertions.check(resp, body)
+	if err == nil {
+		return
+	}
+
+	result.AssertionFailure = err.Error()
+
+	if c.bodiesDir == "" {
+		return
+	}
+
+	path := filepath.Join(c.bodiesDir, fmt.Sprintf("%06d-%d.body", c.saved.Add(1), resp.StatusCode))
+	if err := os.WriteFile(path, body[:min(len(body), maxSavedBodyLength)], 0o644); err != nil {
+		result.AssertionFailure += fmt.Sprintf(" (failed to save body: %v)", err)
+		return
+	}
+
+	result.BodyFile = path
+}
@@ -0,0 +1,156 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAssertionsCheck(t *testing.T) {
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": {"application/json"}}}
+	body := []byte(`{"items": [{"id": 1, "name": "a"}], "content-type": "json", "ok": true}`)
+
+	tests := []struct {
+		assertions Assertions
+		want       string
+	}{
+		{Assertions{Status: []int{200, 204}}, ""},
+		{Assertions{Status: []int{201}}, "status 200 not in [201]"},
+		{Assertions{Headers: []string{"content-type"}}, ""},
+		{Assertions{Headers: []string{"ETag"}}, "header ETag missing"},
+		{Assertions{BodyContains: `"ok": true`}, ""},
+		{Assertions{BodyContains: "error"}, `body does not contain "error"`},
+		{Assertions{BodyMatches: `"id":\s*\d+`}, ""},
+		{Assertions{BodyMatches: `^\[`}, `body does not match "^\\["`},
+		{Assertions{JSONPath: map[string]any{"$.items[0].id": 1, `$["content-type"]`: "json", "$.ok": true}}, ""},
+		{Assertions{JSONPath: map[string]any{"$.items[0].name": "b"}}, `$.items[0].name is "a", want "b"`},
+		{Assertions{JSONPath: map[string]any{"$.items[1].id": 1}}, "$.items[1].id not found"},
+		{Assertions{JSONPath: map[string]any{"$.items": []any{map[string]any{"id": 1, "name": "a"}}}}, ""},
+	}
+
+	for _, tt := range tests {
+		if err := tt.assertions.compile(); err != nil {
+			t.Fatalf("compile(%+v) err = %v", tt.assertions, err)
+		}
+
+		err := tt.assertions.check(resp, body)
+
+		if (err == nil && tt.want != "") || (err != nil && err.Error() != tt.want) {
+			t.Errorf("check(%+v) err = %v; want %q", tt.assertions, err, tt.want)
+		}
+	}
+}
+
+func TestJSONPathEmptyKey(t *testing.T) {
+	segments, err := parseJSONPath(`$[""]`)
+	if err != nil {
+		t.Fatalf("parseJSONPath err = %v; want nil", err)
+	}
+
+	if value, ok := lookupJSONPath(map[string]any{"": "empty"}, segments); !ok || value != "empty" {
+		t.Errorf(`lookupJSONPath({"": "empty"}) = %v, %v; want "empty", true`, value, ok)
+	}
+
+	if value, ok := lookupJSONPath([]any{"first"}, segments); ok {
+		t.Errorf(`lookupJSONPath(["first"]) = %v, %v; want not found`, value, ok)
+	}
+
+	segments, err = parseJSONPath(`$.a[""]`)
+	if err != nil {
+		t.Fatalf("parseJSONPath err = %v; want nil", err)
+	}
+
+	if value, ok := lookupJSONPath(map[string]any{"a": []any{"first"}}, segments); ok {
+		t.Errorf(`lookupJSONPath({"a": ["first"]}) = %v, %v; want not found`, value, ok)
+	}
+}
+
+func TestAssertionsCheckNonJSONBody(t *testing.T) {
+	assertions := Assertions{JSONPath: map[string]any{"$.ok": true}}
+	if err := assertions.compile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := assertions.check(&http.Response{StatusCode: 200}, []byte("OK")); err == nil {
+		t.Errorf("check() err = nil; want error for non JSON body")
+	}
+}
+
+func TestAssertionsCompileInvalid(t *testing.T) {
+	invalid := []Assertions{
+		{BodyMatches: "("},
+		{JSONPath: map[string]any{"items": 1}},
+		{JSONPath: map[string]any{"$.items[x]": 1}},
+		{Headers: []string{"Bad Name"}},
+	}
+
+	for _, assertions := range invalid {
+		if err := assertions.compile(); err == nil {
+			t.Errorf("compile(%+v) err = nil; want error", assertions)
+		}
+	}
+}
+
+func TestLoadAssertions(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.json")
+	if err := os.WriteFile(valid, []byte(`{"status": [200], "jsonPath": {"$.ok": true}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	assertions, err := LoadAssertions(valid)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := assertions.check(&http.Response{StatusCode: 200}, []byte(`{"ok": true}`)); err != nil {
+		t.Errorf("check() err = %v; want nil", err)
+	}
+
+	unknown := filepath.Join(dir, "unknown.json")
+	if err := os.WriteFile(unknown, []byte(`{"statusCode": 200}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadAssertions(unknown); err == nil {
+		t.Errorf("LoadAssertions(%s) err = nil; want error for unknown field", unknown)
+	}
+}
+
+func TestResponseCheckerCapsSavedBodies(t *testing.T) {
+	checker := &responseChecker{assertions: &Assertions{Status: []int{200}}, bodiesDir: t.TempDir()}
+	resp := &http.Response{StatusCode: 500}
+	result := &Result{}
+
+	checker.check(&Request{}, resp, bytes.Repeat([]byte("x"), maxSavedBodyLength+10), result)
+
+	info, err := os.Stat(result.BodyFile)
+	if err != nil {
+		t.Fatalf("saved body err = %v; want nil", err)
+	}
+
+	if info.Size() != maxSavedBodyLength {
+		t.Errorf("saved body size = %d; want %d", info.Size(), maxSavedBodyLength)
+	}
+}
@@ -0,0 +1,327 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Failed token refreshes are retried after this long
+	authRetryInterval = 5 * time.Second
+	// Token requests of ClientCredentials without a client of their own time out after this long
+	tokenRequestTimeout = 30 * time.Second
+)
+
+var defaultTokenClient = &http.Client{Timeout: tokenRequestTimeout}
+
+// TokenSource obtains the token injected into every replayed request
+type TokenSource interface {
+	// Token returns a new token and when it expires, zero when it does not
+	Token(ctx context.Context) (token string, expiry time.Time, err error)
+}
+
+// AuthConfig injects a token into every replayed request, replacing the
+// credentials recorded with it. Auth is off when Source is nil.
+type AuthConfig struct {
+	Source TokenSource
+	// Header the token is set in, "Authorization" when empty
+	Header string
+	// Header value with %s replaced by the token, "Bearer %s" when empty
+	// for the Authorization header and the bare token for other headers
+	Format string
+	// Set the token in this cookie instead of a header
+	Cookie string
+	// Tokens are refreshed this long before they expire
+	RefreshBefore time.Duration
+	// Tokens without an expiry are refreshed at this interval, never when 0
+	RefreshInterval time.Duration
+}
+
+// ClientCredentials obtains tokens with the OAuth2 client credentials grant
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Defaults to a client timing out after 30s
+	Client *http.Client
+}
+
+func (c *ClientCredentials) Token(ctx context.Context) (string, time.Time, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	client := c.Client
+	if client == nil {
+		client = defaultTokenClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, truncate(body, maxDiffBodyLength))
+	}
+
+	return parseToken(body)
+}
+
+// FileToken reads the token from a file, either the bare token or a JSON
+// object with an access_token and optionally expires_in seconds
+type FileToken struct {
+	Path string
+}
+
+func (f *FileToken) Token(context.Context) (string, time.Time, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return parseToken(data)
+}
+
+// CommandToken runs a shell command printing the token in the same formats as FileToken
+type CommandToken struct {
+	Command string
+}
+
+func (c *CommandToken) Token(ctx context.Context) (string, time.Time, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.Command)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("token command failed: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return parseToken(output)
+}
+
+// parseToken accepts a bare token or an OAuth2 token response
+func parseToken(data []byte) (string, time.Time, error) {
+	data = bytes.TrimSpace(data)
+	var expiry time.Time
+
+	if bytes.HasPrefix(data, []byte("{")) {
+		var response struct {
+			AccessToken string  `json:"access_token"`
+			ExpiresIn   float64 `json:"expires_in"`
+		}
+		if err := json.Unmarshal(data, &response); err != nil {
+			return "", time.Time{}, fmt.Errorf("invalid token response: %w", err)
+		}
+
+		if response.ExpiresIn > 0 {
+			expiry = time.Now().Add(time.Duration(response.ExpiresIn * float64(time.Second)))
+		}
+		data = []byte(response.AccessToken)
+	}
+
+	if len(data) == 0 {
+		return "", time.Time{}, fmt.Errorf("empty token")
+	}
+
+	if bytes.ContainsAny(data, "\r\n\x00") {
+		return "", time.Time{}, fmt.Errorf("invalid token: contains line breaks")
+	}
+
+	return string(data), expiry, nil
+}
+
+// authenticator keeps a current token, refreshed in the background, and
+// injects it into requests. It is shared by all workers.
+type authenticator struct {
+	config AuthConfig
+	header string
+	format string
+	token  atomic.Pointer[string]
+}
+
+// newAuthenticator returns nil when auth is off
+func newAuthenticator(config AuthConfig) (*authenticator, error) {
+	if config.Source == nil {
+		return nil, nil
+	}
+
+	a := &authenticator{config: config, header: config.Header, format: config.Format}
+
+	if a.header == "" {
+		a.header = "Authorization"
+	}
+
+	if !validHeaderName(a.header) {
+		return nil, fmt.Errorf("invalid auth header name: %q", a.header)
+	}
+
+	if config.Cookie != "" && !validHeaderName(config.Cookie) {
+		return nil, fmt.Errorf("invalid auth cookie name: %q", config.Cookie)
+	}
+
+	if a.format == "" {
+		a.format = "%s"
+		if strings.EqualFold(a.header, "Authorization") {
+			a.format = "Bearer %s"
+		}
+	}
+
+	if !strings.Contains(a.format, "%s") {
+		return nil, fmt.Errorf("auth format must contain %%s: %q", a.format)
+	}
+
+	if config.RefreshBefore < 0 || config.RefreshInterval < 0 {
+		return nil, fmt.Errorf("auth refresh durations must not be negative")
+	}
+
+	return a, nil
+}
+
+// start obtains the first token, giving up when ctx is done, then refreshes it
+// in the background until refreshCtx is done
+func (a *authenticator) start(ctx, refreshCtx context.Context) error {
+	expiry, err := a.refresh(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to obtain auth token: %w", err)
+	}
+
+	go func() {
+		for {
+			delay := a.refreshDelay(expiry)
+			if delay <= 0 {
+				return
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-refreshCtx.Done():
+				timer.Stop()
+				return
+			}
+
+			newExpiry, err := a.refresh(refreshCtx)
+			if err != nil {
+				if refreshCtx.Err() != nil {
+					return
+				}
+				log.Printf("WARNING: failed to refresh auth token, retrying in %s: %v", authRetryInterval, err)
+				// The current token is kept until a refresh succeeds
+				expiry = time.Now().Add(a.config.RefreshBefore + authRetryInterval)
+				continue
+			}
+			expiry = newExpiry
+		}
+	}()
+
+	return nil
+}
+
+func (a *authenticator) refresh(ctx context.Context) (time.Time, error) {
+	token, expiry, err := a.config.Source.Token(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	a.token.Store(&token)
+	return expiry, nil
+}
+
+// refreshDelay returns how long until the token is refreshed, 0 when it never is
+func (a *authenticator) refreshDelay(expiry time.Time) time.Duration {
+	if expiry.IsZero() {
+		return a.config.RefreshInterval
+	}
+
+	delay := time.Until(expiry) - a.config.RefreshBefore
+	if delay < time.Second {
+		// Tokens living shorter than RefreshBefore are refreshed at most every second
+		return time.Second
+	}
+	return delay
+}
+
+// apply replaces the credentials of req with the current token
+func (a *authenticator) apply(req *http.Request) {
+	token := a.token.Load()
+	if token == nil {
+		return
+	}
+
+	if a.config.Cookie == "" {
+		req.Header.Set(a.header, strings.ReplaceAll(a.format, "%s", *token))
+		return
+	}
+
+	cookies := req.Cookies()
+	req.Header.Del("Cookie")
+
+	for _, cookie := range cookies {
+		if cookie.Name != a.config.Cookie {
+			req.AddCookie(cookie)
+		}
+	}
+
+	req.AddCookie(&http.Cookie{Name: a.config.Cookie, Value: *token})
+}
+
+// authTransport injects the current token into every request it sends
+type authTransport struct {
+	base http.RoundTripper
+	auth *authenticator
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it is given
+	req = req.Clone(req.Context())
+	t.auth.apply(req)
+	return t.base.RoundTrip(req)
+}
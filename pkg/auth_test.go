@@ -0,0 +1,195 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "ripley" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+			return
+		}
+
+		if r.PostFormValue("grant_type") != "client_credentials" || r.PostFormValue("scope") != "read write" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"access_token": "abc", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer server.Close()
+
+	source := &ClientCredentials{TokenURL: server.URL, ClientID: "ripley", ClientSecret: "s3cret", Scopes: []string{"read", "write"}}
+
+	token, expiry, err := source.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() err = %v; want nil", err)
+	}
+
+	if token != "abc" {
+		t.Errorf("token = %s; want abc", token)
+	}
+
+	if remaining := time.Until(expiry); remaining < 59*time.Minute || remaining > time.Hour {
+		t.Errorf("expiry in %s; want in an hour", remaining)
+	}
+
+	source.ClientSecret = "wrong"
+	if _, _, err := source.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Token() err = %v; want 401 error", err)
+	}
+}
+
+func TestFileAndCommandToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("abc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := map[string]TokenSource{
+		"file":    &FileToken{Path: path},
+		"command": &CommandToken{Command: `echo '{"access_token": "abc", "expires_in": 60}'`},
+	}
+
+	for name, source := range sources {
+		token, _, err := source.Token(context.Background())
+		if err != nil || token != "abc" {
+			t.Errorf("%s Token() = %q, %v; want abc", name, token, err)
+		}
+	}
+
+	if _, _, err := (&CommandToken{Command: "echo failed >&2; exit 1"}).Token(context.Background()); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("failing command Token() err = %v; want error with its output", err)
+	}
+}
+
+func TestParseToken(t *testing.T) {
+	invalid := []string{"", "  \n", `{"token_type": "Bearer"}`, `{"access_token": `, "a\nb"}
+
+	for _, data := range invalid {
+		if _, _, err := parseToken([]byte(data)); err == nil {
+			t.Errorf("parseToken(%q) err = nil; want error", data)
+		}
+	}
+
+	token, expiry, err := parseToken([]byte(`{"access_token": "abc"}`))
+	if err != nil || token != "abc" || !expiry.IsZero() {
+		t.Errorf("parseToken() = %q, %v, %v; want abc without expiry", token, expiry, err)
+	}
+}
+
+type tokenFunc func() (string, time.Time, error)
+
+func (f tokenFunc) Token(context.Context) (string, time.Time, error) {
+	return f()
+}
+
+func TestAuthenticatorApply(t *testing.T) {
+	tests := []struct {
+		config AuthConfig
+		header string
+		want   string
+	}{
+		{AuthConfig{}, "Authorization", "Bearer abc"},
+		{AuthConfig{Header: "X-Api-Key"}, "X-Api-Key", "abc"},
+		{AuthConfig{Format: "Token token=%s"}, "Authorization", "Token token=abc"},
+		{AuthConfig{Cookie: "session"}, "Cookie", "theme=dark; session=abc"},
+	}
+
+	for _, test := range tests {
+		test.config.Source = tokenFunc(func() (string, time.Time, error) { return "abc", time.Time{}, nil })
+
+		auth, err := newAuthenticator(test.config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if err := auth.start(context.Background(), context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		req.Header.Set("Authorization", "Bearer expired")
+		req.Header.Set("Cookie", "session=expired; theme=dark")
+		auth.apply(req)
+
+		if got := req.Header.Get(test.header); got != test.want {
+			t.Errorf("%s = %q; want %q", test.header, got, test.want)
+		}
+	}
+}
+
+func TestAuthenticatorRefresh(t *testing.T) {
+	var calls atomic.Int32
+	source := tokenFunc(func() (string, time.Time, error) {
+		calls.Add(1)
+		return "abc", time.Now().Add(1200 * time.Millisecond), nil
+	})
+
+	auth, err := newAuthenticator(AuthConfig{Source: source, RefreshBefore: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := auth.start(context.Background(), ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	cancel()
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("token fetched %d times; want 2", got)
+	}
+}
+
+func TestNewAuthenticatorInvalid(t *testing.T) {
+	source := tokenFunc(func() (string, time.Time, error) { return "abc", time.Time{}, nil })
+
+	configs := []AuthConfig{
+		{Source: source, Header: "Bad Header"},
+		{Source: source, Cookie: "bad;cookie"},
+		{Source: source, Format: "Bearer"},
+		{Source: source, RefreshBefore: -time.Second},
+	}
+
+	for _, config := range configs {
+		if _, err := newAuthenticator(config); err == nil {
+			t.Errorf("newAuthenticator(%+v) err = nil; want error", config)
+		}
+	}
+
+	if auth, err := newAuthenticator(AuthConfig{Header: "Bad Header"}); auth != nil || err != nil {
+		t.Errorf("newAuthenticator without source = %v, %v; want nil, nil", auth, err)
+	}
+}
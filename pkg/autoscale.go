@@ -0,0 +1,116 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AutoscaleConfig grows and shrinks the worker pool while requests are
+// replayed. Autoscaling is off when MaxWorkers is 0.
+type AutoscaleConfig struct {
+	// Bounds of the pool size, which starts at Workers clamped to them
+	MinWorkers int
+	MaxWorkers int
+	// How often the pool size is adjusted
+	Interval time.Duration
+	// The pool grows when requests are sent later than this after their
+	// scheduled time on average
+	MaxSkew time.Duration
+}
+
+// autoscaler adjusts the size of a worker pool. The pool grows by half when
+// requests are sent late or all workers are busy, and gives back half of its
+// idle workers when fewer than half are busy and requests are sent on time.
+type autoscaler struct {
+	minWorkers int
+	maxWorkers int
+	interval   time.Duration
+	maxSkew    time.Duration
+}
+
+// newAutoscaler returns nil when autoscaling is off
+func newAutoscaler(config AutoscaleConfig) (*autoscaler, error) {
+	if config.MaxWorkers == 0 {
+		return nil, nil
+	}
+
+	minWorkers := max(config.MinWorkers, 1)
+
+	if config.MaxWorkers < minWorkers {
+		return nil, fmt.Errorf("max workers must be at least the min workers: %d < %d", config.MaxWorkers, minWorkers)
+	}
+
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("autoscale interval must be positive: %s", config.Interval)
+	}
+
+	if config.MaxSkew < 0 {
+		return nil, fmt.Errorf("autoscale max skew must not be negative: %s", config.MaxSkew)
+	}
+
+	return &autoscaler{
+		minWorkers: minWorkers,
+		maxWorkers: config.MaxWorkers,
+		interval:   config.Interval,
+		maxSkew:    config.MaxSkew,
+	}, nil
+}
+
+// initialSize clamps the configured number of workers to the bounds
+func (a *autoscaler) initialSize(workers int) int {
+	return min(max(workers, a.minWorkers), a.maxWorkers)
+}
+
+// run adjusts the pool size every interval until ctx is done
+func (a *autoscaler) run(ctx context.Context, pool *workerPool) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.adjust(pool)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// adjust starts or stops workers and returns the new pool size
+func (a *autoscaler) adjust(pool *workerPool) int {
+	size := int(pool.size.Load())
+	busy := int(pool.busy.Load())
+	skew, sent := pool.skew()
+	late := sent > 0 && skew > a.maxSkew
+
+	switch {
+	case (late || busy >= size) && size < a.maxWorkers:
+		grown := min(size+max(size/2, 1), a.maxWorkers)
+		pool.start(grown - size)
+		return grown
+	case !late && busy < size/2 && size > a.minWorkers:
+		shrunk := max(size-(size-busy)/2, a.minWorkers)
+		return size - pool.stop(size-shrunk)
+	default:
+		return size
+	}
+}
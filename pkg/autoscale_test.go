@@ -0,0 +1,199 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewAutoscalerInvalid(t *testing.T) {
+	configs := []AutoscaleConfig{
+		{MinWorkers: 4, MaxWorkers: 2, Interval: time.Second},
+		{MaxWorkers: 2},
+		{MaxWorkers: 2, Interval: time.Second, MaxSkew: -time.Second},
+	}
+
+	for _, config := range configs {
+		if _, err := newAutoscaler(config); err == nil {
+			t.Errorf("newAutoscaler(%+v) err = nil; want error", config)
+		}
+	}
+
+	if scaler, err := newAutoscaler(AutoscaleConfig{Interval: time.Second}); scaler != nil || err != nil {
+		t.Errorf("newAutoscaler({}) = %v, %v; want nil, nil", scaler, err)
+	}
+}
+
+func TestAutoscalerInitialSize(t *testing.T) {
+	scaler, err := newAutoscaler(AutoscaleConfig{MinWorkers: 2, MaxWorkers: 8, Interval: time.Second})
+	if err != nil {
+		t.Fatalf("newAutoscaler err = %v; want nil", err)
+	}
+
+	tests := map[int]int{1: 2, 4: 4, 16: 8}
+	for workers, want := range tests {
+		if got := scaler.initialSize(workers); got != want {
+			t.Errorf("initialSize(%d) = %d; want %d", workers, got, want)
+		}
+	}
+}
+
+func TestAutoscalerAdjust(t *testing.T) {
+	scaler, err := newAutoscaler(AutoscaleConfig{MinWorkers: 2, MaxWorkers: 8, Interval: time.Second, MaxSkew: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("newAutoscaler err = %v; want nil", err)
+	}
+
+	requests := make(chan *Request)
+	defer close(requests)
+
+	pool := newWorkerPool(context.Background(), DefaultConfig(), requests, make(chan *Result), &responseChecker{}, nil, nil, nil, &noopRecorder{})
+	pool.start(4)
+
+	// All workers busy
+	pool.busy.Store(4)
+	if size := scaler.adjust(pool); size != 6 || pool.size.Load() != 6 {
+		t.Errorf("adjust() = %d, pool size %d; want 6", size, pool.size.Load())
+	}
+
+	// Requests sent late, growth is capped
+	pool.busy.Store(0)
+	pool.skewTotal.Store(int64(100 * time.Millisecond))
+	pool.skewCount.Store(1)
+	if size := scaler.adjust(pool); size != 8 || pool.size.Load() != 8 {
+		t.Errorf("adjust() = %d, pool size %d; want 8", size, pool.size.Load())
+	}
+
+	// Requests sent on time with most workers idle, wait for the new workers to be idle
+	time.Sleep(50 * time.Millisecond)
+	pool.busy.Store(2)
+	pool.skewTotal.Store(int64(time.Millisecond))
+	pool.skewCount.Store(1)
+	if size := scaler.adjust(pool); size != 5 {
+		t.Errorf("adjust() = %d; want 5", size)
+	}
+
+	// Never below the minimum
+	pool.busy.Store(0)
+	for i := 0; i < 5; i++ {
+		time.Sleep(10 * time.Millisecond)
+		scaler.adjust(pool)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if size := pool.size.Load(); size != 2 {
+		t.Errorf("pool size = %d; want 2", size)
+	}
+}
+
+// poolSizeRecorder records the worker pool sizes reported
+type poolSizeRecorder struct {
+	noopRecorder
+	mu    sync.Mutex
+	sizes []int
+}
+
+func (r *poolSizeRecorder) SetWorkerPoolSize(size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sizes = append(r.sizes, size)
+}
+
+func TestWorkerPoolReportsSize(t *testing.T) {
+	requests := make(chan *Request)
+	defer close(requests)
+
+	results := make(chan *Result, 1)
+	recorder := &poolSizeRecorder{}
+	config := DefaultConfig()
+	config.DryRun = true
+
+	pool := newWorkerPool(context.Background(), config, requests, results, &responseChecker{}, nil, nil, nil, recorder)
+	pool.start(2)
+	pool.spawn(&Request{Method: "GET", Url: "http://localhost"}, 10*time.Millisecond)
+	<-results
+
+	// The spawned worker stops once idle
+	time.Sleep(100 * time.Millisecond)
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if want := []int{2, 3, 2}; !slices.Equal(recorder.sizes, want) {
+		t.Errorf("reported pool sizes = %v; want %v", recorder.sizes, want)
+	}
+}
+
+// yieldingRecorder yields before recording a size, widening any window in
+// which concurrent reports could be reordered
+type yieldingRecorder struct {
+	*poolSizeRecorder
+}
+
+func (r yieldingRecorder) SetWorkerPoolSize(size int) {
+	runtime.Gosched()
+	r.poolSizeRecorder.SetWorkerPoolSize(size)
+}
+
+func TestWorkerPoolReportsSizesInOrder(t *testing.T) {
+	recorder := &poolSizeRecorder{}
+	pool := &workerPool{metricsRecorder: yieldingRecorder{recorder}}
+
+	// Release all the resizes at once, so that they race each other
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if i%2 == 0 {
+				pool.resize(1)
+			} else {
+				pool.resize(-1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if len(recorder.sizes) != 100 {
+		t.Fatalf("len(sizes) = %d; want 100", len(recorder.sizes))
+	}
+
+	// Each reported size differs from the one before by the resize delta
+	previous := 0
+	for i, size := range recorder.sizes {
+		if size-previous != 1 && size-previous != -1 {
+			t.Errorf("sizes[%d] = %d after %d; want a change of 1", i, size, previous)
+		}
+		previous = size
+	}
+
+	if last := recorder.sizes[len(recorder.sizes)-1]; last != 0 {
+		t.Errorf("last size = %d; want 0", last)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// The sliding window is made of this many buckets that expire one at a time
+	breakerBuckets = 10
+	// Merging the window's buckets is not free, so conditions are checked at most this often
+	breakerCheckInterval = 100 * time.Millisecond
+)
+
+// CircuitBreakerConfig holds the conditions under which a run is aborted early
+type CircuitBreakerConfig struct {
+	// Space separated conditions in the same format as SLOs, without a scope,
+	// e.g. "error_rate>50% status_5xx_rate>20% p95>2s"
+	Conditions string
+	// Results are aggregated over this sliding window
+	Window time.Duration
+	// Any of the conditions must hold continuously for this long to abort
+	For time.Duration
+	// Conditions are not checked until the window holds at least this many results
+	MinRequests int
+}
+
+// circuitBreaker watches a sliding window of results and trips once any of its
+// conditions has held for long enough. It is fed by the result handler only.
+type circuitBreaker struct {
+	conditions    []*condition
+	window        time.Duration
+	holdFor       time.Duration
+	minRequests   int
+	bucketWidth   time.Duration
+	buckets       []*breakerBucket
+	breachedSince map[*condition]time.Time // when each condition started to hold
+	lastCheck     time.Time
+	reason        string // written before tripped is closed
+	tripped       chan struct{}
+}
+
+type breakerBucket struct {
+	start time.Time
+	stats *resultStats
+}
+
+// newCircuitBreaker returns nil when no abort conditions are configured
+func newCircuitBreaker(config CircuitBreakerConfig) (*circuitBreaker, error) {
+	conditions, err := parseConditions(config.Conditions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	for _, c := range conditions {
+		if c.scope != "" {
+			return nil, fmt.Errorf("abort conditions cannot be scoped: %s", c.expr)
+		}
+	}
+
+	if config.Window <= 0 {
+		return nil, fmt.Errorf("abort window must be positive: %s", config.Window)
+	}
+
+	return &circuitBreaker{
+		conditions:    conditions,
+		window:        config.Window,
+		holdFor:       config.For,
+		minRequests:   config.MinRequests,
+		bucketWidth:   config.Window / breakerBuckets,
+		breachedSince: make(map[*condition]time.Time, len(conditions)),
+		tripped:       make(chan struct{}),
+	}, nil
+}
+
+func (b *circuitBreaker) record(result *Result) {
+	b.recordAt(result, time.Now())
+}
+
+func (b *circuitBreaker) recordAt(result *Result, now time.Time) {
+	if b == nil || b.isTripped() {
+		return
+	}
+
+	if len(b.buckets) == 0 || now.Sub(b.buckets[len(b.buckets)-1].start) >= b.bucketWidth {
+		b.buckets = append(b.buckets, &breakerBucket{start: now, stats: newResultStats()})
+	}
+	b.buckets[len(b.buckets)-1].stats.record(result, now)
+
+	// Expire buckets that have fallen out of the window
+	for len(b.buckets) > 0 && now.Sub(b.buckets[0].start) > b.window {
+		b.buckets = b.buckets[1:]
+	}
+
+	if now.Sub(b.lastCheck) < breakerCheckInterval {
+		return
+	}
+	b.lastCheck = now
+	b.check(now)
+}
+
+func (b *circuitBreaker) check(now time.Time) {
+	windowStats := newResultStats()
+	for _, bucket := range b.buckets {
+		windowStats.merge(bucket.stats)
+	}
+
+	if windowStats.requests < b.minRequests {
+		clear(b.breachedSince)
+		return
+	}
+
+	stats := windowStats.stats(windowStats.window())
+
+	// Each condition must hold on its own, one taking over from another does not count
+	for _, c := range b.conditions {
+		actual, breached := c.holds(&stats)
+		if !breached {
+			delete(b.breachedSince, c)
+			continue
+		}
+
+		since, ok := b.breachedSince[c]
+		if !ok {
+			since = now
+			b.breachedSince[c] = since
+		}
+
+		if now.Sub(since) >= b.holdFor {
+			b.reason = fmt.Sprintf("Aborted: %s held for %s over the last %s (actual %s)",
+				c.expr, now.Sub(since).Round(time.Millisecond), b.window, c.format(actual))
+			close(b.tripped)
+			return
+		}
+	}
+}
+
+func (b *circuitBreaker) isTripped() bool {
+	if b == nil {
+		return false
+	}
+
+	select {
+	case <-b.tripped:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,183 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCircuitBreakerDisabled(t *testing.T) {
+	breaker, err := newCircuitBreaker(CircuitBreakerConfig{})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if breaker != nil {
+		t.Errorf("breaker = %v; want nil", breaker)
+	}
+
+	// A nil breaker is safe to use and never trips
+	breaker.record(&Result{ErrorMsg: "boom", Request: &Request{}})
+
+	if breaker.isTripped() {
+		t.Errorf("breaker.isTripped() = true; want false")
+	}
+}
+
+func TestNewCircuitBreakerInvalid(t *testing.T) {
+	configs := []CircuitBreakerConfig{
+		{Conditions: "error_rate>", Window: time.Second},
+		{Conditions: "phase[1].error_rate>50%", Window: time.Second},
+		{Conditions: "error_rate>50%"},
+	}
+
+	for _, config := range configs {
+		if _, err := newCircuitBreaker(config); err == nil {
+			t.Errorf("newCircuitBreaker(%+v) err = nil; want error", config)
+		}
+	}
+}
+
+func TestCircuitBreakerTripsAfterHoldDuration(t *testing.T) {
+	breaker, err := newCircuitBreaker(CircuitBreakerConfig{
+		Conditions:  "status_5xx_rate>50%",
+		Window:      10 * time.Second,
+		For:         2 * time.Second,
+		MinRequests: 5,
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	req := &Request{Url: "http://localhost:8080/"}
+	start := time.Now()
+
+	// Failing results every 200ms, the condition first holds at 800ms once 5 results are in
+	for i := 0; i < 14; i++ {
+		breaker.recordAt(&Result{StatusCode: 503, Request: req}, start.Add(time.Duration(i)*200*time.Millisecond))
+	}
+
+	if breaker.isTripped() {
+		t.Fatalf("breaker tripped after 1.8s of breach; want condition to hold for 2s first")
+	}
+
+	for i := 14; i < 20; i++ {
+		breaker.recordAt(&Result{StatusCode: 503, Request: req}, start.Add(time.Duration(i)*200*time.Millisecond))
+	}
+
+	if !breaker.isTripped() {
+		t.Fatalf("breaker.isTripped() = false; want true")
+	}
+
+	if !strings.HasPrefix(breaker.reason, "Aborted: status_5xx_rate>50% held for 2s") {
+		t.Errorf("breaker.reason = %q", breaker.reason)
+	}
+}
+
+func TestCircuitBreakerResetsWhenConditionClears(t *testing.T) {
+	breaker, err := newCircuitBreaker(CircuitBreakerConfig{
+		Conditions: "error_rate>50%",
+		Window:     time.Second,
+		For:        1500 * time.Millisecond,
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	req := &Request{Url: "http://localhost:8080/"}
+	start := time.Now()
+
+	// Alternate between one second of errors and one second of successes
+	for i := 0; i < 40; i++ {
+		result := &Result{StatusCode: 200, Request: req}
+		if (i/10)%2 == 0 {
+			result = &Result{ErrorMsg: "connection refused", Request: req}
+		}
+		breaker.recordAt(result, start.Add(time.Duration(i)*100*time.Millisecond))
+	}
+
+	if breaker.isTripped() {
+		t.Errorf("breaker tripped; want reset whenever the error rate drops")
+	}
+}
+
+func TestCircuitBreakerTracksConditionsSeparately(t *testing.T) {
+	breaker, err := newCircuitBreaker(CircuitBreakerConfig{
+		Conditions: "status_5xx_rate>40% error_rate>40%",
+		Window:     time.Second,
+		For:        1500 * time.Millisecond,
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	req := &Request{Url: "http://localhost:8080/"}
+	start := time.Now()
+
+	// After a second of successes, alternate between one second of 503s and
+	// one second of errors, so one of the conditions always holds but neither
+	// holds for 1.5s
+	for i := 0; i < 50; i++ {
+		result := &Result{StatusCode: 200, Request: req}
+		switch {
+		case i < 10:
+		case (i/10)%2 == 1:
+			result = &Result{StatusCode: 503, Request: req}
+		default:
+			result = &Result{ErrorMsg: "connection refused", Request: req}
+		}
+		breaker.recordAt(result, start.Add(time.Duration(i)*100*time.Millisecond))
+	}
+
+	if breaker.isTripped() {
+		t.Errorf("breaker tripped with %q; want each condition to hold for 1.5s on its own", breaker.reason)
+	}
+}
+
+func TestCircuitBreakerWindowExpiry(t *testing.T) {
+	breaker, err := newCircuitBreaker(CircuitBreakerConfig{
+		Conditions: "errors>0",
+		Window:     time.Second,
+		For:        time.Hour,
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	req := &Request{Url: "http://localhost:8080/"}
+	start := time.Now()
+
+	breaker.recordAt(&Result{ErrorMsg: "boom", Request: req}, start)
+	breaker.recordAt(&Result{StatusCode: 200, Request: req}, start.Add(2*time.Second))
+
+	if len(breaker.buckets) != 1 {
+		t.Errorf("len(breaker.buckets) = %v; want 1", len(breaker.buckets))
+	}
+
+	if len(breaker.breachedSince) != 0 {
+		t.Errorf("breaker.breachedSince = %v; want empty after the error left the window", breaker.breachedSince)
+	}
+}
@@ -19,8 +19,11 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package ripley
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,65 +32,258 @@ type Result struct {
 	Latency    time.Duration `json:"latency"`
 	Request    *Request      `json:"Request"`
 	ErrorMsg   string        `json:"error"`
+	Phase      int           `json:"phase"`
+	Rate       float64       `json:"rate"`
+	// The first assertion the response failed, the request itself succeeded
+	AssertionFailure string `json:"assertionFailure,omitempty"`
+	// Where the body of a response failing its assertions was saved
+	BodyFile string `json:"bodyFile,omitempty"`
+	// In shadow mode, the result is the candidate's and these compare it to the baseline
+	Baseline    *BaselineResult `json:"baseline,omitempty"`
+	Differences []Difference    `json:"differences,omitempty"`
+	// Latency breakdown of requests that got a response
+	Timings *Timings `json:"timings,omitempty"`
+	// When the pacer scheduled the request to be sent, and when a worker sent it
+	ScheduledAt time.Time `json:"scheduledAt"`
+	SentAt      time.Time `json:"sentAt"`
+	// From the scheduled send time to completion, unlike Latency this includes
+	// the time the request waited for a busy worker
+	CorrectedLatency time.Duration `json:"correctedLatency"`
+
+	// Reported for input that could not be parsed as a request
+	invalid bool
+}
+
+// workerPool sends the requests it receives and reports their results.
+// In-flight requests are cancelled when ctx is done.
+type workerPool struct {
+	ctx       context.Context
+	client    *http.Client
+	requests  <-chan *Request
+	results   chan<- *Result
+	dryRun    bool
+	checker   *responseChecker
+	shadow    *shadowDiffer
+	templates *templater
+	// Number of worker goroutines, and of those sending a request
+	size atomic.Int64
+	busy atomic.Int64
+	// How late requests were sent after their scheduled time, since the
+	// autoscaler last looked
+	skewTotal atomic.Int64
+	skewCount atomic.Int64
+	// Idle workers stop when they receive from this channel
+	retire chan struct{}
+	// Reports the pool size as workers start and stop, under resizeMu so
+	// that concurrent resizes are reported in the order they happened
+	metricsRecorder MetricsRecorder
+	resizeMu        sync.Mutex
 }
 
-func startClientWorkers(numWorkers int, requests <-chan *Request, results chan<- *Result, dryRun bool, timeout, connections, maxConnections int, disableKeepAlives bool) {
+func newWorkerPool(ctx context.Context, config Config, requests <-chan *Request, results chan<- *Result, checker *responseChecker, shadow *shadowDiffer, auth *authenticator, templates *templater, metricsRecorder MetricsRecorder) *workerPool {
+	var transport http.RoundTripper = &http.Transport{
+		MaxIdleConnsPerHost: config.Connections,
+		MaxConnsPerHost:     config.MaxConnections,
+		DisableKeepAlives:   config.DisableKeepAlives,
+	}
+
+	if auth != nil {
+		transport = &authTransport{base: transport, auth: auth}
+	}
+
 	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
+		Timeout: config.Timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
-		Transport: &http.Transport{
-			MaxIdleConnsPerHost: connections,
-			MaxConnsPerHost:     maxConnections,
-			DisableKeepAlives:   disableKeepAlives,
-		},
+		Transport: transport,
+	}
+
+	return &workerPool{
+		ctx:             ctx,
+		client:          client,
+		requests:        requests,
+		results:         results,
+		dryRun:          config.DryRun,
+		checker:         checker,
+		shadow:          shadow,
+		templates:       templates,
+		retire:          make(chan struct{}),
+		metricsRecorder: metricsRecorder,
 	}
+}
+
+// resize adds delta to the pool size and reports the new size
+func (p *workerPool) resize(delta int) {
+	p.resizeMu.Lock()
+	defer p.resizeMu.Unlock()
+	size := p.size.Add(int64(delta))
+	p.metricsRecorder.SetWorkerPoolSize(int(size))
+}
 
-	for i := 0; i < numWorkers; i++ {
-		go doHttpRequest(client, requests, results, dryRun)
+// start starts n workers, which stop once the requests channel is closed
+func (p *workerPool) start(n int) {
+	p.resize(n)
+	for i := 0; i < n; i++ {
+		go p.work(nil, 0)
 	}
 }
 
-func doHttpRequest(client *http.Client, requests <-chan *Request, results chan<- *Result, dryRun bool) {
-	for req := range requests {
-		latencyStart := time.Now()
+// spawn starts a worker sending req, which then keeps handling requests
+// until it has been idle for idleTimeout
+func (p *workerPool) spawn(req *Request, idleTimeout time.Duration) {
+	p.resize(1)
+	go p.work(req, idleTimeout)
+}
 
-		if dryRun {
-			sendResult(req, &http.Response{}, latencyStart, "", results)
-		} else {
-			executeRequest(client, req, latencyStart, results)
+// stop stops up to n idle workers and returns how many were stopped
+func (p *workerPool) stop(n int) int {
+	for i := 0; i < n; i++ {
+		select {
+		case p.retire <- struct{}{}:
+		default:
+			return i
 		}
 	}
+	return n
+}
+
+// skew returns the mean delay between the scheduled and actual send time of
+// the requests sent since it was last called, and how many there were
+func (p *workerPool) skew() (time.Duration, int64) {
+	total := p.skewTotal.Swap(0)
+	count := p.skewCount.Swap(0)
+	if count == 0 {
+		return 0, 0
+	}
+	return time.Duration(total / count), count
 }
 
-func executeRequest(client *http.Client, req *Request, latencyStart time.Time, results chan<- *Result) {
+func (p *workerPool) work(first *Request, idleTimeout time.Duration) {
+	defer p.resize(-1)
+
+	if first != nil {
+		p.handle(first)
+	}
+
+	// Workers started with the pool never stop for being idle
+	var idle <-chan time.Time
+	var timer *time.Timer
+	if idleTimeout > 0 {
+		timer = time.NewTimer(idleTimeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+
+	for {
+		select {
+		case req, ok := <-p.requests:
+			if !ok {
+				return
+			}
+			p.handle(req)
+			if timer != nil {
+				timer.Reset(idleTimeout)
+			}
+		case <-idle:
+			return
+		case <-p.retire:
+			return
+		}
+	}
+}
+
+func (p *workerPool) handle(req *Request) {
+	p.busy.Add(1)
+	defer p.busy.Add(-1)
+
+	// Rendered before the shadow copies are made, so both targets get the same values
+	if p.templates != nil {
+		rendered, err := p.templates.render(req)
+		if err != nil {
+			sendResult(req, &http.Response{}, time.Now(), err.Error(), p.results)
+			return
+		}
+		req = rendered
+	}
+
+	latencyStart := time.Now()
+
+	if !req.scheduled.IsZero() {
+		p.skewTotal.Add(int64(max(latencyStart.Sub(req.scheduled), 0)))
+		p.skewCount.Add(1)
+	}
+
+	if p.dryRun {
+		sendResult(req, &http.Response{}, latencyStart, "", p.results)
+	} else if p.shadow != nil {
+		p.results <- p.shadow.execute(p.ctx, p.client, req, p.checker)
+	} else {
+		executeRequest(p.ctx, p.client, req, latencyStart, p.results, p.checker)
+	}
+}
+
+func executeRequest(ctx context.Context, client *http.Client, req *Request, latencyStart time.Time, results chan<- *Result, checker *responseChecker) {
+	result, resp, body := fetch(ctx, client, req, latencyStart)
+
+	// Assertions are checked after the latency is taken, so they do not add to it
+	if resp != nil {
+		checker.check(req, resp, body, result)
+	}
+
+	results <- result
+}
+
+// fetch sends req and reads the whole response body. The response is nil
+// when the request failed, in which case the result holds the error.
+func fetch(ctx context.Context, client *http.Client, req *Request, latencyStart time.Time) (*Result, *http.Response, []byte) {
 	httpReq, err := req.httpRequest()
 	if err != nil {
-		sendResult(req, &http.Response{}, latencyStart, err.Error(), results)
-		return
+		return newResult(req, &http.Response{}, latencyStart, err.Error()), nil, nil
 	}
 
-	resp, err := client.Do(httpReq)
+	trace := &timingTrace{}
+	resp, err := client.Do(httpReq.WithContext(trace.withContext(ctx)))
 	if err != nil {
-		sendResult(req, &http.Response{}, latencyStart, err.Error(), results)
-		return
+		return newResult(req, &http.Response{}, latencyStart, err.Error()), nil, nil
 	}
 
-	_, err = io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
 	if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
 		err = closeErr
 	}
 
 	if err != nil {
-		sendResult(req, &http.Response{}, latencyStart, err.Error(), results)
-		return
+		return newResult(req, &http.Response{}, latencyStart, err.Error()), nil, nil
 	}
 
-	sendResult(req, resp, latencyStart, "", results)
+	result := newResult(req, resp, latencyStart, "")
+	result.Timings = trace.timings(latencyStart, latencyStart.Add(result.Latency))
+	return result, resp, body
 }
 
 func sendResult(req *Request, resp *http.Response, latencyStart time.Time, err string, results chan<- *Result) {
+	results <- newResult(req, resp, latencyStart, err)
+}
+
+func newResult(req *Request, resp *http.Response, latencyStart time.Time, err string) *Result {
 	latency := time.Since(latencyStart)
-	results <- &Result{StatusCode: resp.StatusCode, Latency: latency, Request: req, ErrorMsg: err}
+
+	// Requests sent without the pacer, e.g. by tests, count as sent on time
+	scheduled := req.scheduled
+	if scheduled.IsZero() || scheduled.After(latencyStart) {
+		scheduled = latencyStart
+	}
+
+	return &Result{
+		StatusCode:       resp.StatusCode,
+		Latency:          latency,
+		Request:          req,
+		ErrorMsg:         err,
+		Phase:            req.phase,
+		Rate:             req.rate,
+		ScheduledAt:      scheduled,
+		SentAt:           latencyStart,
+		CorrectedLatency: latency + latencyStart.Sub(scheduled),
+	}
 }
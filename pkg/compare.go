@@ -0,0 +1,200 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// Distinct paths are capped like hosts, paths often embed IDs
+	maxComparedPaths = 100
+	otherPaths       = "other"
+	// Latency ratios are recorded in a histogram as millionths
+	ratioScale = 1e6
+)
+
+// Comparison compares the replayed responses against the original responses
+// recorded in the requests' expectedStatus and originalLatency
+type Comparison struct {
+	ComparisonStats
+	// Keyed by host and path, e.g. "localhost:8080/api/users"
+	Paths map[string]*ComparisonStats `json:"paths"`
+}
+
+// ComparisonStats holds the comparison of the whole run or a single path
+type ComparisonStats struct {
+	// Results of requests with an expectedStatus
+	StatusCompared     int     `json:"statusCompared"`
+	StatusMismatches   int     `json:"statusMismatches"`
+	StatusMismatchRate float64 `json:"statusMismatchRate"`
+	// Mismatches by expected and replayed status, e.g. "200->503" or "200->error"
+	Mismatches map[string]int `json:"mismatches"`
+	// Successful results of requests with an originalLatency
+	LatencyCompared int `json:"latencyCompared"`
+	// Replayed latency divided by the original latency
+	LatencyRatio RatioSummary `json:"latencyRatio"`
+}
+
+// RatioSummary holds the distribution of latency ratios, above 1 the replay
+// was slower than the original
+type RatioSummary struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// comparisonCollector aggregates comparisons globally and per host and path.
+// It is not safe for concurrent use.
+type comparisonCollector struct {
+	total *comparisonStats
+	paths map[string]*comparisonStats
+}
+
+func newComparisonCollector() *comparisonCollector {
+	return &comparisonCollector{
+		total: newComparisonStats(),
+		paths: make(map[string]*comparisonStats),
+	}
+}
+
+func (c *comparisonCollector) record(result *Result) {
+	req := result.Request
+	if req.ExpectedStatus == 0 && req.OriginalLatency <= 0 {
+		return
+	}
+
+	c.total.record(result)
+
+	path := extractHostPath(req.Url)
+	pathStats, ok := c.paths[path]
+	if !ok {
+		if len(c.paths) >= maxComparedPaths {
+			path = otherPaths
+			pathStats = c.paths[path]
+		}
+		if pathStats == nil {
+			pathStats = newComparisonStats()
+			c.paths[path] = pathStats
+		}
+	}
+	pathStats.record(result)
+}
+
+// comparison returns nil when no request had an original response to compare
+func (c *comparisonCollector) comparison() *Comparison {
+	if len(c.paths) == 0 {
+		return nil
+	}
+
+	comparison := &Comparison{
+		ComparisonStats: c.total.stats(),
+		Paths:           make(map[string]*ComparisonStats, len(c.paths)),
+	}
+
+	for path, pathStats := range c.paths {
+		stats := pathStats.stats()
+		comparison.Paths[path] = &stats
+	}
+
+	return comparison
+}
+
+type comparisonStats struct {
+	statusCompared   int
+	statusMismatches int
+	mismatches       map[string]int
+	latencyRatio     *histogram
+}
+
+func newComparisonStats() *comparisonStats {
+	return &comparisonStats{
+		mismatches:   make(map[string]int),
+		latencyRatio: newHistogram(),
+	}
+}
+
+func (s *comparisonStats) record(result *Result) {
+	req := result.Request
+
+	if req.ExpectedStatus != 0 {
+		s.statusCompared++
+
+		if result.ErrorMsg != "" || result.StatusCode != req.ExpectedStatus {
+			replayed := "error"
+			if result.ErrorMsg == "" {
+				replayed = strconv.Itoa(result.StatusCode)
+			}
+
+			s.statusMismatches++
+			s.mismatches[fmt.Sprintf("%d->%s", req.ExpectedStatus, replayed)]++
+		}
+	}
+
+	if req.OriginalLatency > 0 && result.ErrorMsg == "" {
+		ratio := float64(result.Latency) / float64(req.OriginalLatency)
+		s.latencyRatio.record(time.Duration(ratio * ratioScale))
+	}
+}
+
+func (s *comparisonStats) stats() ComparisonStats {
+	stats := ComparisonStats{
+		StatusCompared:   s.statusCompared,
+		StatusMismatches: s.statusMismatches,
+		Mismatches:       make(map[string]int, len(s.mismatches)),
+		LatencyCompared:  int(s.latencyRatio.count()),
+		LatencyRatio: RatioSummary{
+			Min:  ratioOf(s.latencyRatio.minimum()),
+			Mean: ratioOf(s.latencyRatio.mean()),
+			P50:  ratioOf(s.latencyRatio.percentile(50)),
+			P90:  ratioOf(s.latencyRatio.percentile(90)),
+			P99:  ratioOf(s.latencyRatio.percentile(99)),
+			Max:  ratioOf(s.latencyRatio.maximum()),
+		},
+	}
+
+	for mismatch, count := range s.mismatches {
+		stats.Mismatches[mismatch] = count
+	}
+
+	if s.statusCompared > 0 {
+		stats.StatusMismatchRate = float64(s.statusMismatches) / float64(s.statusCompared)
+	}
+
+	return stats
+}
+
+func ratioOf(scaled time.Duration) float64 {
+	return float64(scaled) / ratioScale
+}
+
+// extractHostPath returns the host and path of a URL, without the query
+func extractHostPath(urlStr string) string {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil || parsedURL.Host == "" {
+		return "unknown"
+	}
+	return parsedURL.Host + parsedURL.EscapedPath()
+}
@@ -0,0 +1,119 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestComparisonCollector(t *testing.T) {
+	collector := newSummaryCollector()
+	users := &Request{Url: "http://example.com/users?id=1", ExpectedStatus: 200, OriginalLatency: 10 * time.Millisecond}
+	orders := &Request{Url: "http://example.com/orders", ExpectedStatus: 201}
+
+	for i := 0; i < 3; i++ {
+		collector.record(&Result{StatusCode: 200, Latency: 20 * time.Millisecond, Request: users})
+	}
+	collector.record(&Result{StatusCode: 503, Latency: 5 * time.Millisecond, Request: users})
+	collector.record(&Result{ErrorMsg: "connection refused", Request: orders})
+	collector.record(&Result{StatusCode: 200, Request: &Request{Url: "http://example.com/uncompared"}})
+
+	comparison := collector.summary(time.Second, 1).Comparison
+	if comparison == nil {
+		t.Fatalf("summary.Comparison = nil; want comparison")
+	}
+
+	if comparison.StatusCompared != 5 || comparison.StatusMismatches != 2 {
+		t.Errorf("comparison = %d of %d mismatches; want 2 of 5", comparison.StatusMismatches, comparison.StatusCompared)
+	}
+
+	if comparison.Mismatches["200->503"] != 1 || comparison.Mismatches["201->error"] != 1 {
+		t.Errorf("comparison.Mismatches = %v; want map[200->503:1 201->error:1]", comparison.Mismatches)
+	}
+
+	if comparison.LatencyCompared != 4 {
+		t.Errorf("comparison.LatencyCompared = %v; want 4", comparison.LatencyCompared)
+	}
+
+	if !approximately(comparison.LatencyRatio.P50, 2) || !approximately(comparison.LatencyRatio.Min, 0.5) {
+		t.Errorf("comparison.LatencyRatio = %+v; want p50 2 and min 0.5", comparison.LatencyRatio)
+	}
+
+	if len(comparison.Paths) != 2 {
+		t.Fatalf("comparison.Paths = %v; want 2 paths", comparison.Paths)
+	}
+
+	if stats := comparison.Paths["example.com/users"]; stats == nil || stats.StatusMismatchRate != 0.25 {
+		t.Errorf("comparison.Paths[example.com/users] = %+v; want mismatch rate 0.25", stats)
+	}
+}
+
+func TestComparisonCollectorWithoutOriginalResponses(t *testing.T) {
+	collector := newSummaryCollector()
+	collector.record(&Result{StatusCode: 200, Request: &Request{Url: "http://example.com/"}})
+
+	if comparison := collector.summary(time.Second, 1).Comparison; comparison != nil {
+		t.Errorf("summary.Comparison = %+v; want nil", comparison)
+	}
+}
+
+func TestComparisonCollectorCapsPaths(t *testing.T) {
+	collector := newComparisonCollector()
+
+	for i := 0; i < maxComparedPaths+10; i++ {
+		collector.record(&Result{StatusCode: 200, Request: &Request{Url: fmt.Sprintf("http://example.com/%d", i), ExpectedStatus: 200}})
+	}
+
+	comparison := collector.comparison()
+
+	if len(comparison.Paths) != maxComparedPaths+1 {
+		t.Errorf("len(comparison.Paths) = %v; want %v", len(comparison.Paths), maxComparedPaths+1)
+	}
+
+	if comparison.Paths[otherPaths].StatusCompared != 10 {
+		t.Errorf("comparison.Paths[other].StatusCompared = %v; want 10", comparison.Paths[otherPaths].StatusCompared)
+	}
+}
+
+func TestSummaryWriteComparison(t *testing.T) {
+	collector := newSummaryCollector()
+	req := &Request{Url: "http://example.com/users", ExpectedStatus: 200, OriginalLatency: time.Millisecond}
+	collector.record(&Result{StatusCode: 500, Latency: 2 * time.Millisecond, Request: req})
+
+	var text bytes.Buffer
+	if err := collector.summary(time.Second, 1).WriteText(&text); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, want := range []string{"Compared to original responses:\n", "200->500", "example.com/users"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text summary missing %q:\n%s", want, text.String())
+		}
+	}
+}
+
+// approximately allows for the 1% relative error of the histogram
+func approximately(actual, expected float64) bool {
+	return math.Abs(actual-expected) <= expected*0.01
+}
@@ -0,0 +1,271 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	conditionPattern = regexp.MustCompile(`^(?:(phase|host)\[([^\]]+)\]\.)?([a-z0-9_]+)(<=|>=|<|>)(.+)$`)
+	statusPattern    = regexp.MustCompile(`^status_([1-5](?:[0-9]{2}|xx))$`)
+)
+
+type metricKind int
+
+const (
+	countMetric metricKind = iota
+	rateMetric
+	latencyMetric
+)
+
+// condition compares an aggregated metric against a threshold, optionally
+// scoped to a single pace phase or target host. Conditions are used both as
+// SLOs that must hold at the end of the run and as abort conditions.
+type condition struct {
+	expr      string
+	scope     string // "", "phase" or "host"
+	phase     int
+	host      string
+	metric    string
+	kind      metricKind
+	op        string
+	threshold float64 // nanoseconds for latency metrics, a ratio for rate metrics
+}
+
+// Format is [scope.]metric[op]threshold, separated by spaces
+// e.g. "p99<300ms error_rate<0.5% phase[2].status_5xx<10 host[localhost:8080].p95<=1s"
+func parseConditions(conditionsStr string) ([]*condition, error) {
+	var conditions []*condition
+
+	for _, expr := range strings.Fields(conditionsStr) {
+		c, err := parseCondition(expr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		conditions = append(conditions, c)
+	}
+
+	return conditions, nil
+}
+
+func parseCondition(expr string) (*condition, error) {
+	tokens := conditionPattern.FindStringSubmatch(expr)
+	if tokens == nil {
+		return nil, fmt.Errorf("invalid condition: %s", expr)
+	}
+
+	c := &condition{expr: expr, scope: tokens[1], metric: tokens[3], op: tokens[4]}
+
+	switch c.scope {
+	case "phase":
+		phase, err := strconv.Atoi(tokens[2])
+		if err != nil || phase < 0 {
+			return nil, fmt.Errorf("invalid condition phase: %s", expr)
+		}
+		c.phase = phase
+	case "host":
+		c.host = tokens[2]
+	}
+
+	kind, err := metricKindOf(c.metric)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %s: %w", expr, err)
+	}
+	c.kind = kind
+
+	threshold := tokens[5]
+
+	switch kind {
+	case latencyMetric:
+		d, err := time.ParseDuration(threshold)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition threshold %s: %w", expr, err)
+		}
+		c.threshold = float64(d)
+	case rateMetric:
+		percent := strings.HasSuffix(threshold, "%")
+		v, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition threshold %s: %w", expr, err)
+		}
+		if percent {
+			v /= 100
+		}
+		c.threshold = v
+	default:
+		v, err := strconv.ParseFloat(threshold, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition threshold %s: %w", expr, err)
+		}
+		c.threshold = v
+	}
+
+	return c, nil
+}
+
+func metricKindOf(metric string) (metricKind, error) {
+	switch strings.TrimPrefix(metric, "corrected_") {
+	case "min", "mean", "p50", "p90", "p95", "p99", "p999", "max":
+		return latencyMetric, nil
+	}
+
+	switch metric {
+	case "requests", "errors", "assertion_failures", "rps":
+		return countMetric, nil
+	case "error_rate", "assertion_failure_rate":
+		return rateMetric, nil
+	}
+
+	if statusPattern.MatchString(strings.TrimSuffix(metric, "_rate")) {
+		if strings.HasSuffix(metric, "_rate") {
+			return rateMetric, nil
+		}
+		return countMetric, nil
+	}
+
+	return 0, fmt.Errorf("unknown metric: %s", metric)
+}
+
+// evaluateSLOs returns a violation message for every SLO the summary breaches
+func evaluateSLOs(slos []*condition, summary *Summary) []string {
+	var violations []string
+
+	for _, slo := range slos {
+		stats := slo.scopeStats(summary)
+		if stats == nil || stats.TotalRequests == 0 {
+			violations = append(violations, fmt.Sprintf("SLO %s violated: no results", slo.expr))
+			continue
+		}
+
+		if actual, ok := slo.holds(stats); !ok {
+			violations = append(violations, fmt.Sprintf("SLO %s violated: actual %s", slo.expr, slo.format(actual)))
+		}
+	}
+
+	return violations
+}
+
+// holds reports whether the condition is true for the stats, along with the
+// actual value of the metric
+func (c *condition) holds(stats *Stats) (float64, bool) {
+	actual := c.value(stats)
+
+	switch c.op {
+	case "<":
+		return actual, actual < c.threshold
+	case "<=":
+		return actual, actual <= c.threshold
+	case ">":
+		return actual, actual > c.threshold
+	default:
+		return actual, actual >= c.threshold
+	}
+}
+
+func (c *condition) scopeStats(summary *Summary) *Stats {
+	switch c.scope {
+	case "phase":
+		for _, phase := range summary.Phases {
+			if phase.Phase == c.phase {
+				return &phase.Stats
+			}
+		}
+		return nil
+	case "host":
+		return summary.Hosts[c.host]
+	default:
+		return &summary.Stats
+	}
+}
+
+func (c *condition) value(stats *Stats) float64 {
+	if c.kind == latencyMetric {
+		if metric, ok := strings.CutPrefix(c.metric, "corrected_"); ok {
+			return latencyValue(&stats.CorrectedLatency, metric)
+		}
+		return latencyValue(&stats.Latency, c.metric)
+	}
+
+	switch c.metric {
+	case "requests":
+		return float64(stats.TotalRequests)
+	case "errors":
+		return float64(stats.Errors)
+	case "rps":
+		return stats.AchievedRPS
+	case "error_rate":
+		return float64(stats.Errors) / float64(stats.TotalRequests)
+	case "assertion_failures":
+		return float64(stats.AssertionFailures)
+	case "assertion_failure_rate":
+		return float64(stats.AssertionFailures) / float64(stats.TotalRequests)
+	}
+
+	class := statusPattern.FindStringSubmatch(strings.TrimSuffix(c.metric, "_rate"))[1]
+	count := 0
+	for code, n := range stats.StatusCodes {
+		if code == class || (strings.HasSuffix(class, "xx") && code[0] == class[0]) {
+			count += n
+		}
+	}
+
+	if c.kind == rateMetric {
+		return float64(count) / float64(stats.TotalRequests)
+	}
+	return float64(count)
+}
+
+func latencyValue(latency *LatencySummary, metric string) float64 {
+	switch metric {
+	case "min":
+		return float64(latency.Min)
+	case "mean":
+		return float64(latency.Mean)
+	case "p50":
+		return float64(latency.P50)
+	case "p90":
+		return float64(latency.P90)
+	case "p95":
+		return float64(latency.P95)
+	case "p99":
+		return float64(latency.P99)
+	case "p999":
+		return float64(latency.P999)
+	default:
+		return float64(latency.Max)
+	}
+}
+
+func (c *condition) format(v float64) string {
+	switch c.kind {
+	case latencyMetric:
+		return time.Duration(v).String()
+	case rateMetric:
+		return fmt.Sprintf("%.3f%%", v*100)
+	default:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSLOs(t *testing.T) {
+	slos, err := parseConditions("p99<300ms error_rate<0.5% phase[2].status_5xx<10 host[localhost:8080].status_503_rate<=0.01")
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(slos) != 4 {
+		t.Fatalf("len(slos) = %v; want 4", len(slos))
+	}
+
+	if slos[0].metric != "p99" || slos[0].op != "<" || slos[0].threshold != float64(300*time.Millisecond) {
+		t.Errorf("slos[0] = %+v; want p99 < 300ms", slos[0])
+	}
+
+	if slos[1].kind != rateMetric || slos[1].threshold != 0.005 {
+		t.Errorf("slos[1] = %+v; want error_rate < 0.005", slos[1])
+	}
+
+	if slos[2].scope != "phase" || slos[2].phase != 2 || slos[2].kind != countMetric {
+		t.Errorf("slos[2] = %+v; want phase 2 status_5xx count", slos[2])
+	}
+
+	if slos[3].scope != "host" || slos[3].host != "localhost:8080" || slos[3].op != "<=" || slos[3].threshold != 0.01 {
+		t.Errorf("slos[3] = %+v; want host localhost:8080 status_503_rate <= 0.01", slos[3])
+	}
+}
+
+func TestParseInvalidSLOs(t *testing.T) {
+	for _, expr := range []string{"p99", "p99<fast", "latency<1s", "phase[x].p99<1s", "status_600<1", "errors<many", "corrected_errors<1"} {
+		if _, err := parseConditions(expr); err == nil {
+			t.Errorf("parseConditions(%q) err = nil; want error", expr)
+		}
+	}
+}
+
+func TestEvaluateSLOs(t *testing.T) {
+	collector := newSummaryCollector()
+	req := &Request{Url: "http://localhost:8080/"}
+
+	for i := 0; i < 98; i++ {
+		collector.record(&Result{StatusCode: 200, Latency: 100 * time.Millisecond, CorrectedLatency: 300 * time.Millisecond, Request: req, Phase: 0})
+	}
+	collector.record(&Result{StatusCode: 503, Latency: 500 * time.Millisecond, Request: req, Phase: 1})
+	collector.record(&Result{ErrorMsg: "timeout", Request: req, Phase: 1})
+
+	summary := collector.summary(time.Second, 0)
+
+	slos, err := parseConditions("p50<200ms error_rate<=1% status_5xx<2 phase[0].max<200ms status_2xx>=98 " +
+		"p999<200ms error_rate<0.5% phase[1].status_5xx_rate<10% host[other:80].requests>0 phase[5].errors<1 corrected_p50<200ms")
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	violations := evaluateSLOs(slos, summary)
+
+	expected := []string{
+		"SLO p999<200ms violated: actual",
+		"SLO error_rate<0.5% violated: actual 1.000%",
+		"SLO phase[1].status_5xx_rate<10% violated: actual 50.000%",
+		"SLO host[other:80].requests>0 violated: no results",
+		"SLO phase[5].errors<1 violated: no results",
+		"SLO corrected_p50<200ms violated: actual 30",
+	}
+
+	if len(violations) != len(expected) {
+		t.Fatalf("violations = %v; want %d violations", violations, len(expected))
+	}
+
+	for i, want := range expected {
+		if !strings.HasPrefix(violations[i], want) {
+			t.Errorf("violations[%d] = %q; want prefix %q", i, violations[i], want)
+		}
+	}
+}
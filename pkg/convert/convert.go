@@ -0,0 +1,85 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package convert holds what converters from other log formats to ripley
+// requests have in common, so they rewrite requests identically
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/url"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+)
+
+// ErrSkipLine is returned by converters for lines that hold no request,
+// e.g. comments and header lines
+var ErrSkipLine = errors.New("line holds no request")
+
+// Converter converts a single line of a log format to a ripley request
+type Converter interface {
+	Convert(line []byte, options Options) (*ripley.Request, error)
+}
+
+// RecordReader is implemented by converters of formats that are not line
+// based, e.g. HAR files, to split their input into the records passed to Convert
+type RecordReader interface {
+	Records(r io.Reader) iter.Seq2[[]byte, error]
+}
+
+// Options are applied to every converted request, whatever the input format
+type Options struct {
+	// Replaces the host of every URL when set, e.g. "localhost:8080"
+	Host string
+	// Replaces specific original hosts, takes precedence over Host
+	HostMap map[string]string
+	// Upgrades http URLs to https
+	HTTPS bool
+}
+
+// MapHost returns the host requests to the original host are sent to
+func (o Options) MapHost(host string) string {
+	if mapped, ok := o.HostMap[host]; ok {
+		return mapped
+	}
+
+	if o.Host != "" {
+		return o.Host
+	}
+
+	return host
+}
+
+// RewriteURL applies the host mapping and HTTPS upgrade to rawURL
+func (o Options) RewriteURL(rawURL string) (string, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL %s: %w", rawURL, err)
+	}
+
+	parsedURL.Host = o.MapHost(parsedURL.Host)
+
+	if o.HTTPS && parsedURL.Scheme == "http" {
+		parsedURL.Scheme = "https"
+	}
+
+	return parsedURL.String(), nil
+}
@@ -0,0 +1,94 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package convert
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestRewriteURL(t *testing.T) {
+	tests := []struct {
+		options Options
+		rawURL  string
+		want    string
+	}{
+		{Options{}, "http://test.com/path", "http://test.com/path"},
+		{Options{Host: "localhost:8080"}, "http://test.com/path?query=value", "http://localhost:8080/path?query=value"},
+		{Options{HTTPS: true}, "http://test.com/path", "https://test.com/path"},
+		{Options{Host: "localhost:8443", HTTPS: true}, "http://test.com:80/", "https://localhost:8443/"},
+		{Options{HTTPS: true}, "https://test.com/", "https://test.com/"},
+		{Options{Host: "localhost:8080", HostMap: map[string]string{"cdn.test.com": "localhost:8081"}}, "http://cdn.test.com/a.js", "http://localhost:8081/a.js"},
+		{Options{Host: "localhost:8080", HostMap: map[string]string{"cdn.test.com": "localhost:8081"}}, "http://test.com/", "http://localhost:8080/"},
+		{Options{HostMap: map[string]string{"cdn.test.com": "localhost:8081"}}, "http://test.com/", "http://test.com/"},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.options.RewriteURL(tt.rawURL)
+
+		if err != nil {
+			t.Errorf("%+v.RewriteURL(%s) err = %v; want nil", tt.options, tt.rawURL, err)
+		}
+
+		if got != tt.want {
+			t.Errorf("%+v.RewriteURL(%s) = %s; want %s", tt.options, tt.rawURL, got, tt.want)
+		}
+	}
+}
+
+func TestRewriteInvalidURL(t *testing.T) {
+	if _, err := (Options{Host: "localhost"}).RewriteURL("://invalid"); err == nil {
+		t.Errorf("RewriteURL(://invalid) err = nil; want error")
+	}
+}
+
+func TestRegisterFlags(t *testing.T) {
+	var options Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	options.RegisterFlags(fs)
+
+	err := fs.Parse([]string{"-host", "localhost:8080", "-https", "-host-map", "a.test.com=localhost:1,b.test.com=localhost:2", "-host-map", "c.test.com=localhost:3"})
+	if err != nil {
+		t.Fatalf("fs.Parse err = %v; want nil", err)
+	}
+
+	if options.Host != "localhost:8080" || !options.HTTPS {
+		t.Errorf("options = %+v; want host localhost:8080 and https", options)
+	}
+
+	if len(options.HostMap) != 3 || options.HostMap["b.test.com"] != "localhost:2" || options.HostMap["c.test.com"] != "localhost:3" {
+		t.Errorf("options.HostMap = %v; want 3 mappings", options.HostMap)
+	}
+
+	if got := fs.Lookup("host-map").Value.String(); got != "a.test.com=localhost:1,b.test.com=localhost:2,c.test.com=localhost:3" {
+		t.Errorf("host-map = %s", got)
+	}
+}
+
+func TestRegisterFlagsInvalidHostMap(t *testing.T) {
+	var options Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	options.RegisterFlags(fs)
+
+	if err := fs.Parse([]string{"-host-map", "a.test.com"}); err == nil {
+		t.Errorf("fs.Parse err = nil; want error")
+	}
+}
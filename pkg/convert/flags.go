@@ -0,0 +1,64 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package convert
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RegisterFlags adds the flags shared by all converters to fs
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Host, "host", o.Host, "New host to replace the original host in URLs")
+	fs.Var((*hostMapFlag)(&o.HostMap), "host-map", `Comma separated original=new host pairs, e.g. "api.example.com=localhost:8080,cdn.example.com=localhost:8081"`)
+	fs.BoolVar(&o.HTTPS, "https", o.HTTPS, "Upgrade HTTP requests to HTTPS")
+}
+
+type hostMapFlag map[string]string
+
+func (f *hostMapFlag) String() string {
+	if f == nil {
+		return ""
+	}
+
+	var pairs []string
+	for original, mapped := range *f {
+		pairs = append(pairs, original+"="+mapped)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f *hostMapFlag) Set(value string) error {
+	if *f == nil {
+		*f = make(hostMapFlag)
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		original, mapped, ok := strings.Cut(pair, "=")
+		if !ok || original == "" || mapped == "" {
+			return fmt.Errorf("invalid host mapping %q, want original=new", pair)
+		}
+		(*f)[original] = mapped
+	}
+
+	return nil
+}
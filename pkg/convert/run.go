@@ -0,0 +1,128 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+)
+
+// Number of failures reported individually by Stats.Report
+const maxReportedFailures = 10
+
+// Stats counts the outcome of a conversion
+type Stats struct {
+	Records   int
+	Converted int
+	Skipped   int
+	Failed    int
+	// The first failures, as "record N: error"
+	Failures []string
+}
+
+// Run converts the records read from r and writes them to w as ripley JSONL.
+// Records that fail to convert are counted rather than stopping the run, the
+// error is only returned when reading or writing fails.
+func Run(r io.Reader, w io.Writer, conv Converter, options Options) (*Stats, error) {
+	records := lines(r)
+	if reader, ok := conv.(RecordReader); ok {
+		records = reader.Records(r)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	stats := &Stats{}
+
+	for record, err := range records {
+		if err != nil {
+			return stats, err
+		}
+
+		stats.Records++
+
+		req, err := conv.Convert(record, options)
+		if errors.Is(err, ErrSkipLine) {
+			stats.Skipped++
+			continue
+		}
+
+		if err != nil {
+			stats.Failed++
+			if len(stats.Failures) < maxReportedFailures {
+				stats.Failures = append(stats.Failures, fmt.Sprintf("record %d: %v", stats.Records, err))
+			}
+			continue
+		}
+
+		if err := encoder.Encode(req); err != nil {
+			return stats, err
+		}
+
+		stats.Converted++
+	}
+
+	return stats, nil
+}
+
+// lines yields the non empty lines of r, without surrounding white space
+func lines(r io.Reader) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 32*1024*1024)
+
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+
+			if !yield(line, nil) {
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// Report writes the counts and the first failures to w
+func (s *Stats) Report(w io.Writer) error {
+	var report strings.Builder
+
+	fmt.Fprintf(&report, "converted %d of %d records, %d failed, %d skipped\n", s.Converted, s.Records, s.Failed, s.Skipped)
+
+	for _, failure := range s.Failures {
+		fmt.Fprintf(&report, "  %s\n", failure)
+	}
+
+	if more := s.Failed - len(s.Failures); more > 0 {
+		fmt.Fprintf(&report, "  ... and %d more failures\n", more)
+	}
+
+	_, err := io.WriteString(w, report.String())
+	return err
+}
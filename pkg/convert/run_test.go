@@ -0,0 +1,137 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package convert
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"iter"
+	"strings"
+	"testing"
+	"time"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+)
+
+// lineConverter treats each line as a URL, skipping comments
+type lineConverter struct{}
+
+func (lineConverter) Convert(line []byte, options Options) (*ripley.Request, error) {
+	text := string(line)
+
+	if strings.HasPrefix(text, "#") {
+		return nil, ErrSkipLine
+	}
+
+	if !strings.HasPrefix(text, "http") {
+		return nil, errors.New("not a URL: " + text)
+	}
+
+	url, err := options.RewriteURL(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ripley.Request{Method: "GET", Url: url, Timestamp: time.Date(2025, 9, 3, 15, 30, 32, 0, time.UTC)}, nil
+}
+
+// recordConverter reads comma separated records
+type recordConverter struct {
+	lineConverter
+}
+
+func (recordConverter) Records(r io.Reader) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, record := range strings.Split(string(data), ",") {
+			if !yield([]byte(record), nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	input := "# comment\nhttp://test.com/a\n\nnot a url\nhttp://test.com/b\n"
+
+	var output bytes.Buffer
+	stats, err := Run(strings.NewReader(input), &output, lineConverter{}, Options{Host: "localhost:8080"})
+
+	if err != nil {
+		t.Fatalf("Run err = %v; want nil", err)
+	}
+
+	want := `{"method":"GET","url":"http://localhost:8080/a","body":"","timestamp":"2025-09-03T15:30:32Z","headers":null}
+{"method":"GET","url":"http://localhost:8080/b","body":"","timestamp":"2025-09-03T15:30:32Z","headers":null}
+`
+	if output.String() != want {
+		t.Errorf("output = %s; want %s", output.String(), want)
+	}
+
+	if stats.Records != 4 || stats.Converted != 2 || stats.Skipped != 1 || stats.Failed != 1 {
+		t.Errorf("stats = %+v; want 4 records, 2 converted, 1 skipped, 1 failed", stats)
+	}
+
+	if len(stats.Failures) != 1 || stats.Failures[0] != "record 3: not a URL: not a url" {
+		t.Errorf("stats.Failures = %q", stats.Failures)
+	}
+}
+
+func TestRunRecordReader(t *testing.T) {
+	var output bytes.Buffer
+	stats, err := Run(strings.NewReader("http://test.com/a,http://test.com/b"), &output, recordConverter{}, Options{})
+
+	if err != nil {
+		t.Fatalf("Run err = %v; want nil", err)
+	}
+
+	if stats.Converted != 2 || strings.Count(output.String(), "\n") != 2 {
+		t.Errorf("stats = %+v, output = %s; want 2 requests", stats, output.String())
+	}
+}
+
+func TestStatsReport(t *testing.T) {
+	input := strings.Repeat("bad\n", maxReportedFailures+2) + "http://test.com/\n"
+
+	stats, err := Run(strings.NewReader(input), io.Discard, lineConverter{}, Options{})
+	if err != nil {
+		t.Fatalf("Run err = %v; want nil", err)
+	}
+
+	var report bytes.Buffer
+	if err := stats.Report(&report); err != nil {
+		t.Fatalf("Report err = %v; want nil", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+
+	if lines[0] != "converted 1 of 13 records, 12 failed, 0 skipped" {
+		t.Errorf("lines[0] = %s", lines[0])
+	}
+
+	if len(lines) != maxReportedFailures+2 || lines[len(lines)-1] != "  ... and 2 more failures" {
+		t.Errorf("report = %s", report.String())
+	}
+}
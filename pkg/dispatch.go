@@ -0,0 +1,85 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Workers started on demand in the open model stop after being idle this long
+const spawnedWorkerIdleTimeout = time.Second
+
+// dispatcher hands requests to idle workers. In the closed model it waits for
+// a worker to become idle when all are busy, which delays the request. In the
+// open model it starts a new worker instead, up to maxInFlight requests in
+// flight, then either waits or drops the request.
+type dispatcher struct {
+	requests     chan<- *Request
+	pool         *workerPool
+	maxInFlight  int64
+	dropWhenFull bool
+	inFlight     atomic.Int64
+	// Only touched by the goroutine dispatching requests
+	delayed int
+	dropped int
+}
+
+// dispatch returns false when the request was dropped or ctx is done
+func (d *dispatcher) dispatch(ctx context.Context, req *Request) bool {
+	if d.maxInFlight > 0 && d.inFlight.Load() >= d.maxInFlight {
+		if d.dropWhenFull {
+			d.dropped++
+			return false
+		}
+	} else {
+		d.inFlight.Add(1)
+
+		select {
+		case d.requests <- req:
+			return true
+		default:
+		}
+
+		if d.maxInFlight > 0 {
+			d.pool.spawn(req, spawnedWorkerIdleTimeout)
+			return true
+		}
+
+		d.inFlight.Add(-1)
+	}
+
+	// No worker is idle and none can be started
+	d.delayed++
+	d.inFlight.Add(1)
+
+	select {
+	case d.requests <- req:
+		return true
+	case <-ctx.Done():
+		d.inFlight.Add(-1)
+		return false
+	}
+}
+
+// done is called for every result of a dispatched request
+func (d *dispatcher) done() {
+	d.inFlight.Add(-1)
+}
@@ -0,0 +1,186 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Filter selects the requests to replay, the others are skipped before they
+// reach the pacer. All conditions given must hold for a request to be replayed.
+type Filter struct {
+	// The method must be one of these
+	Methods []string
+	// Regular expressions the URL host and the full URL must match
+	Host string
+	URL  string
+	// Regular expressions a value of each header must match, keyed by header name
+	Headers map[string]string
+	// Requests whose URL matches any of these regular expressions are skipped,
+	// e.g. health checks
+	Drop []string
+	// Percentage of requests replayed, selected by a hash of the URL so the
+	// same URLs are replayed on every run. Sampling is off when 0.
+	SamplePercent float64
+}
+
+// requestFilter is the compiled form of a Filter
+type requestFilter struct {
+	methods []string
+	host    *regexp.Regexp
+	url     *regexp.Regexp
+	headers map[string]*regexp.Regexp
+	drop    []*regexp.Regexp
+	// Hashes below this are sampled, out of sampleBuckets
+	sampleBelow uint64
+}
+
+const sampleBuckets = 1_000_000
+
+// newRequestFilter returns nil when the filter has no conditions
+func newRequestFilter(filter Filter) (*requestFilter, error) {
+	f := &requestFilter{headers: make(map[string]*regexp.Regexp, len(filter.Headers))}
+	enabled := false
+
+	for _, method := range filter.Methods {
+		if !validMethod(strings.ToUpper(method)) {
+			return nil, fmt.Errorf("invalid method: %s", method)
+		}
+		f.methods = append(f.methods, strings.ToUpper(method))
+		enabled = true
+	}
+
+	var err error
+
+	if f.host, err = compileOptional(filter.Host); err != nil {
+		return nil, fmt.Errorf("invalid host filter: %w", err)
+	}
+
+	if f.url, err = compileOptional(filter.URL); err != nil {
+		return nil, fmt.Errorf("invalid URL filter: %w", err)
+	}
+
+	for name, pattern := range filter.Headers {
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("invalid header name: %q", name)
+		}
+		if f.headers[name], err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid filter for header %s: %w", name, err)
+		}
+	}
+
+	for _, pattern := range filter.Drop {
+		drop, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid drop filter: %w", err)
+		}
+		f.drop = append(f.drop, drop)
+	}
+
+	if filter.SamplePercent < 0 || filter.SamplePercent > 100 {
+		return nil, fmt.Errorf("sample percentage must be between 0 and 100: %v", filter.SamplePercent)
+	}
+
+	if filter.SamplePercent > 0 {
+		f.sampleBelow = uint64(filter.SamplePercent / 100 * sampleBuckets)
+	} else {
+		f.sampleBelow = sampleBuckets
+	}
+
+	if !enabled && f.host == nil && f.url == nil && len(f.headers) == 0 && len(f.drop) == 0 && f.sampleBelow == sampleBuckets {
+		return nil, nil
+	}
+
+	return f, nil
+}
+
+// matches reports whether req is to be replayed
+func (f *requestFilter) matches(req *Request) bool {
+	if len(f.methods) > 0 && !slices.Contains(f.methods, req.Method) {
+		return false
+	}
+
+	if f.host != nil {
+		parsedURL, err := url.Parse(req.Url)
+		if err != nil || !f.host.MatchString(parsedURL.Host) {
+			return false
+		}
+	}
+
+	if f.url != nil && !f.url.MatchString(req.Url) {
+		return false
+	}
+
+	for name, pattern := range f.headers {
+		if !slices.ContainsFunc(headerValues(req.Headers, name), pattern.MatchString) {
+			return false
+		}
+	}
+
+	for _, drop := range f.drop {
+		if drop.MatchString(req.Url) {
+			return false
+		}
+	}
+
+	if f.sampleBelow < sampleBuckets {
+		hash := fnv.New64a()
+		_, _ = hash.Write([]byte(req.Url))
+		if hash.Sum64()%sampleBuckets >= f.sampleBelow {
+			return false
+		}
+	}
+
+	return true
+}
+
+// headerValues returns the values of every spelling of a header name
+func headerValues(headers Headers, name string) []string {
+	var values []string
+	for key, keyValues := range headers {
+		if strings.EqualFold(key, name) {
+			values = append(values, keyValues...)
+		}
+	}
+	return values
+}
+
+// filterSource skips the requests of a source the filter does not select.
+// Invalid requests are passed on, so they are still reported.
+type filterSource struct {
+	source   RequestSource
+	filter   *requestFilter
+	filtered atomic.Int64
+}
+
+func (s *filterSource) Next() (*Request, error) {
+	for {
+		req, err := s.source.Next()
+		if err != nil || s.filter.matches(req) {
+			return req, err
+		}
+		s.filtered.Add(1)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRequestFilter(t *testing.T) {
+	filter, err := newRequestFilter(Filter{
+		Methods: []string{"get", "HEAD"},
+		Host:    `^api\.example\.com$`,
+		URL:     "/users",
+		Headers: map[string]string{"X-Tenant": "^acme$"},
+		Drop:    []string{"/health"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	acme := Headers{"x-tenant": {"other", "acme"}}
+
+	tests := []struct {
+		req  *Request
+		want bool
+	}{
+		{&Request{Method: "GET", Url: "http://api.example.com/users/1", Headers: acme}, true},
+		{&Request{Method: "HEAD", Url: "http://api.example.com/users", Headers: acme}, true},
+		{&Request{Method: "POST", Url: "http://api.example.com/users/1", Headers: acme}, false},
+		{&Request{Method: "GET", Url: "http://www.example.com/users/1", Headers: acme}, false},
+		{&Request{Method: "GET", Url: "http://api.example.com/orders", Headers: acme}, false},
+		{&Request{Method: "GET", Url: "http://api.example.com/users/1", Headers: Headers{"X-Tenant": {"other"}}}, false},
+		{&Request{Method: "GET", Url: "http://api.example.com/users/1"}, false},
+		{&Request{Method: "GET", Url: "http://api.example.com/users/health", Headers: acme}, false},
+	}
+
+	for _, test := range tests {
+		if got := filter.matches(test.req); got != test.want {
+			t.Errorf("matches(%s %s) = %v; want %v", test.req.Method, test.req.Url, got, test.want)
+		}
+	}
+}
+
+func TestRequestFilterSample(t *testing.T) {
+	filter, err := newRequestFilter(Filter{SamplePercent: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		req := &Request{Method: "GET", Url: fmt.Sprintf("http://example.com/users/%d", i)}
+
+		if filter.matches(req) {
+			sampled++
+		}
+	}
+
+	if sampled < 900 || sampled > 1100 {
+		t.Errorf("sampled %d of 10000 requests; want about 1000", sampled)
+	}
+}
+
+func TestNewRequestFilter(t *testing.T) {
+	if filter, err := newRequestFilter(Filter{}); filter != nil || err != nil {
+		t.Errorf("newRequestFilter({}) = %v, %v; want nil, nil", filter, err)
+	}
+
+	invalid := []Filter{
+		{Methods: []string{"FETCH"}},
+		{Host: "("},
+		{URL: "("},
+		{Headers: map[string]string{"Bad Header": "x"}},
+		{Headers: map[string]string{"X-Tenant": "("}},
+		{Drop: []string{"("}},
+		{SamplePercent: -1},
+		{SamplePercent: 101},
+	}
+
+	for _, filter := range invalid {
+		if _, err := newRequestFilter(filter); err == nil {
+			t.Errorf("newRequestFilter(%+v) err = nil; want error", filter)
+		}
+	}
+}
+
+func TestReplayFilter(t *testing.T) {
+	now := time.Now()
+	input := ""
+	for i, path := range []string{"/users", "/health", "/users/1", "/health"} {
+		input += `{"url": "http://example.com` + path + `", "method": "GET", "timestamp": "` + now.Add(time.Duration(i)*10*time.Millisecond).Format(time.RFC3339Nano) + `"}` + "\n"
+	}
+	input += "not json\n"
+
+	config := testConfig("1s@1", time.Second, 1, 1)
+	config.DryRun = true
+	config.Filter = Filter{Drop: []string{"/health$"}}
+
+	summary, err := runReplay(t, config, input)
+	if err == nil {
+		t.Errorf("err = nil; want invalid input error")
+	}
+
+	if summary.TotalRequests != 2 || summary.FilteredRequests != 2 || summary.InvalidRequests != 1 {
+		t.Errorf("summary = %d requests, %d filtered and %d invalid; want 2, 2 and 1", summary.TotalRequests, summary.FilteredRequests, summary.InvalidRequests)
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Number of linear sub-buckets per power of two. 128 sub-buckets keep the
+// relative error of any recorded value below 1%.
+const (
+	subBucketBits  = 7
+	subBucketCount = 1 << subBucketBits
+)
+
+// histogram is a log-linear latency histogram in the spirit of HdrHistogram.
+// Values below 2*subBucketCount nanoseconds are counted exactly, larger values
+// fall into buckets whose width doubles every subBucketCount buckets, so memory
+// stays bounded no matter how many values are recorded.
+type histogram struct {
+	counts []uint64
+	total  uint64
+	sum    float64
+	min    int64
+	max    int64
+}
+
+func newHistogram() *histogram {
+	return &histogram{min: math.MaxInt64}
+}
+
+func (h *histogram) record(d time.Duration) {
+	v := int64(d)
+	if v < 0 {
+		v = 0
+	}
+
+	idx := bucketIndex(v)
+	if idx >= len(h.counts) {
+		counts := make([]uint64, idx+1)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+
+	h.counts[idx]++
+	h.total++
+	h.sum += float64(v)
+
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// merge adds all values recorded in o to h
+func (h *histogram) merge(o *histogram) {
+	if len(o.counts) > len(h.counts) {
+		counts := make([]uint64, len(o.counts))
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+
+	for idx, c := range o.counts {
+		h.counts[idx] += c
+	}
+
+	h.total += o.total
+	h.sum += o.sum
+
+	if o.min < h.min {
+		h.min = o.min
+	}
+	if o.max > h.max {
+		h.max = o.max
+	}
+}
+
+func (h *histogram) count() uint64 {
+	return h.total
+}
+
+func (h *histogram) minimum() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.min)
+}
+
+func (h *histogram) maximum() time.Duration {
+	return time.Duration(h.max)
+}
+
+func (h *histogram) mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / float64(h.total))
+}
+
+// percentile returns the value below which q percent of the recorded values fall
+func (h *histogram) percentile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+
+	target := uint64(math.Ceil(q / 100 * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+
+	var cumulative uint64
+	for idx, c := range h.counts {
+		cumulative += c
+		if cumulative >= target {
+			v := bucketMidpoint(idx)
+			// The bucket midpoint can stray outside the observed range
+			if v < h.min {
+				v = h.min
+			}
+			if v > h.max {
+				v = h.max
+			}
+			return time.Duration(v)
+		}
+	}
+
+	return time.Duration(h.max)
+}
+
+func bucketIndex(v int64) int {
+	shift := bits.Len64(uint64(v)) - (subBucketBits + 1)
+	if shift < 0 {
+		shift = 0
+	}
+	return shift*subBucketCount + int(v>>shift)
+}
+
+func bucketMidpoint(idx int) int64 {
+	if idx < 2*subBucketCount {
+		return int64(idx)
+	}
+	shift := idx/subBucketCount - 1
+	lowest := int64(idx-shift*subBucketCount) << shift
+	return lowest + (int64(1)<<shift)/2
+}
@@ -22,6 +22,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -40,6 +41,27 @@ var (
 		[]string{"host"},
 	)
 
+	// Request phase duration histogram, phases of reused connections are not observed
+	// Note: Uses host (not full URL) to prevent high cardinality issues
+	requestPhaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ripley_request_phase_duration_seconds",
+			Help:    "HTTP request phase latencies in seconds by phase (dns, connect, tls, ttfb, transfer) and target host",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"phase", "host"},
+	)
+
+	// Connections counter
+	// Note: Uses host (not full URL) to prevent high cardinality issues
+	connectionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ripley_connections_total",
+			Help: "Total number of connections requests were sent on by target host and whether the connection was reused",
+		},
+		[]string{"host", "reused"},
+	)
+
 	// Response status code counter
 	// Note: Uses host (not full URL) to prevent high cardinality issues
 	responseStatus = prometheus.NewCounterVec(
@@ -68,6 +90,16 @@ var (
 		[]string{"host"},
 	)
 
+	// Assertion failures counter
+	// Note: Uses host (not full URL) to prevent high cardinality issues
+	assertionFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ripley_assertion_failures_total",
+			Help: "Total number of responses failing their assertions by target host",
+		},
+		[]string{"host"},
+	)
+
 	// Pacer phase gauge
 	pacerPhase = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -112,6 +144,7 @@ type MetricsConfig struct {
 type MetricsRecorder interface {
 	RecordRequest(result *Result)
 	StartMonitoring(requests chan *Request, results chan *Result) func()
+	SetWorkerPoolSize(size int)
 }
 
 // prometheusRecorder implements MetricsRecorder with actual Prometheus metrics
@@ -151,18 +184,27 @@ func (p *prometheusRecorder) StartMonitoring(requests chan *Request, results cha
 	}
 }
 
+func (p *prometheusRecorder) SetWorkerPoolSize(size int) {
+	SetWorkerPoolSize(size)
+}
+
 func (n *noopRecorder) RecordRequest(result *Result) {}
 
 func (n *noopRecorder) StartMonitoring(requests chan *Request, results chan *Result) func() {
 	return func() {} // Return no-op cleanup function
 }
 
+func (n *noopRecorder) SetWorkerPoolSize(size int) {}
+
 func init() {
 	// Register metrics with Prometheus's default registry
 	prometheus.MustRegister(requestDuration)
+	prometheus.MustRegister(requestPhaseDuration)
+	prometheus.MustRegister(connectionsTotal)
 	prometheus.MustRegister(responseStatus)
 	prometheus.MustRegister(requestsTotal)
 	prometheus.MustRegister(errorsTotal)
+	prometheus.MustRegister(assertionFailuresTotal)
 	prometheus.MustRegister(pacerPhase)
 	prometheus.MustRegister(workerPoolSize)
 	prometheus.MustRegister(requestQueueSize)
@@ -221,6 +263,34 @@ func RecordRequest(result *Result) {
 	} else {
 		requestDuration.WithLabelValues(host).Observe(result.Latency.Seconds())
 		responseStatus.WithLabelValues(http.StatusText(result.StatusCode), host).Inc()
+
+		if result.AssertionFailure != "" {
+			assertionFailuresTotal.WithLabelValues(host).Inc()
+		}
+
+		if result.Timings != nil {
+			recordTimings(host, result.Timings)
+		}
+	}
+}
+
+func recordTimings(host string, timings *Timings) {
+	connectionsTotal.WithLabelValues(host, strconv.FormatBool(timings.ConnReused)).Inc()
+	requestPhaseDuration.WithLabelValues("ttfb", host).Observe(timings.TTFB.Seconds())
+	requestPhaseDuration.WithLabelValues("transfer", host).Observe(timings.Transfer.Seconds())
+
+	if timings.ConnReused {
+		return
+	}
+
+	if timings.DNS > 0 {
+		requestPhaseDuration.WithLabelValues("dns", host).Observe(timings.DNS.Seconds())
+	}
+	if timings.Connect > 0 {
+		requestPhaseDuration.WithLabelValues("connect", host).Observe(timings.Connect.Seconds())
+	}
+	if timings.TLS > 0 {
+		requestPhaseDuration.WithLabelValues("tls", host).Observe(timings.TLS.Seconds())
 	}
 }
 
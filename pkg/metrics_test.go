@@ -123,6 +123,7 @@ func TestPrometheusRecorder_RecordRequest(t *testing.T) {
 		Request:    req,
 		StatusCode: 200,
 		Latency:    50 * time.Millisecond,
+		Timings:    &Timings{Connect: time.Millisecond, TTFB: 40 * time.Millisecond, Transfer: 10 * time.Millisecond},
 	}
 
 	// Record a request
@@ -155,6 +156,14 @@ func TestPrometheusRecorder_RecordRequest(t *testing.T) {
 		t.Error("ripley_response_status_total metric not found")
 	}
 
+	if !strings.Contains(metrics, `ripley_request_phase_duration_seconds_count{host="test.example.com",phase="connect"} 1`) {
+		t.Error("ripley_request_phase_duration_seconds metric not found for the connect phase")
+	}
+
+	if !strings.Contains(metrics, `ripley_connections_total{host="test.example.com",reused="false"} 1`) {
+		t.Error("ripley_connections_total metric not found")
+	}
+
 	// Verify host label is used (not full URL)
 	if !strings.Contains(metrics, `host="test.example.com"`) {
 		t.Error("Expected host label with value 'test.example.com'")
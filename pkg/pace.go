@@ -20,6 +20,8 @@ package ripley
 
 import (
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"strconv"
 	"strings"
@@ -29,8 +31,11 @@ import (
 
 type pacer struct {
 	ReportInterval        time.Duration
+	out                   io.Writer    // where stats are reported
 	mu                    sync.RWMutex // protects all fields below
 	phases                []*phase
+	phaseIndex            int       // index of phases[0] in the original pace specification
+	lastRate              float64   // rate at the end of the last elapsed phase
 	lastRequestTime       time.Time // last Request that we already replayed in "log time"
 	lastRequestWallTime   time.Time // last Request that we already replayed in "wall time"
 	phaseStartRequestTime time.Time
@@ -38,11 +43,90 @@ type pacer struct {
 	done                  bool
 	requestCounter        int
 	nextReport            time.Time
+	firstRequestWallTime  time.Time // "wall time" the first Request was scheduled for
+	scheduledRequests     int
 }
 
+type rampKind int
+
+const (
+	// The rate is constant over the phase
+	rampNone rampKind = iota
+	// The rate changes by the same amount every second
+	rampLinear
+	// The rate changes by the same factor every second
+	rampExponential
+)
+
 type phase struct {
 	duration time.Duration
 	rate     float64
+	// Ramp phases change the rate continuously from rate to endRate
+	endRate float64
+	ramp    rampKind
+}
+
+// rateAt returns the rate once elapsed wall time of the phase has passed
+func (ph *phase) rateAt(elapsed time.Duration) float64 {
+	progress := min(max(elapsed.Seconds()/ph.duration.Seconds(), 0), 1)
+
+	switch ph.ramp {
+	case rampLinear:
+		return ph.rate + (ph.endRate-ph.rate)*progress
+	case rampExponential:
+		return ph.rate * math.Pow(ph.endRate/ph.rate, progress)
+	default:
+		return ph.rate
+	}
+}
+
+// wallOffset returns when, from the start of the phase in wall time, a request
+// logged offset after the phase's first request is due. Ramp phases integrate
+// their rate over wall time, and carry on at endRate past their duration.
+func (ph *phase) wallOffset(offset time.Duration) time.Duration {
+	if ph.ramp == rampNone {
+		return time.Duration(float64(offset) / ph.rate)
+	}
+
+	// Requests logged before the start of the phase are due straight away
+	if offset <= 0 {
+		return 0
+	}
+
+	logged := offset.Seconds()
+	duration := ph.duration.Seconds()
+	r0, r1 := ph.rate, ph.endRate
+
+	var loggedAtEnd, wall float64
+
+	switch ph.ramp {
+	case rampLinear:
+		// logged = r0*wall + slope*wall²/2, solved for wall in a form that
+		// holds for a zero slope or start rate
+		slope := (r1 - r0) / duration
+		loggedAtEnd = (r0 + r1) / 2 * duration
+		wall = 2 * logged / (r0 + math.Sqrt(max(r0*r0+2*slope*logged, 0)))
+	case rampExponential:
+		// logged = r0*(e^(growth*wall)-1)/growth
+		growth := math.Log(r1/r0) / duration
+		if growth == 0 {
+			loggedAtEnd = r0 * duration
+			wall = logged / r0
+		} else {
+			loggedAtEnd = (r1 - r0) / growth
+			wall = math.Log1p(growth*logged/r0) / growth
+		}
+	}
+
+	if logged >= loggedAtEnd {
+		// Ramps down to 0 send nothing more until the phase elapses
+		wall = duration
+		if r1 > 0 {
+			wall += (logged - loggedAtEnd) / r1
+		}
+	}
+
+	return time.Duration(wall * float64(time.Second))
 }
 
 func newPacer(phasesStr string) (*pacer, error) {
@@ -52,7 +136,7 @@ func newPacer(phasesStr string) (*pacer, error) {
 		return nil, err
 	}
 
-	return &pacer{phases: phases}, nil
+	return &pacer{phases: phases, out: os.Stderr}, nil
 }
 
 func (p *pacer) start() {
@@ -74,7 +158,9 @@ func (p *pacer) onPhaseElapsed() {
 
 	// Pop phase
 	if len(p.phases) > 0 {
+		p.lastRate = p.phases[0].rateAt(p.phases[0].duration)
 		p.phases = p.phases[1:]
+		p.phaseIndex++
 	}
 	p.phaseStartRequestTime = p.lastRequestTime
 	p.phaseStartWallTime = p.lastRequestWallTime
@@ -87,7 +173,9 @@ func (p *pacer) onPhaseElapsed() {
 	}
 }
 
-func (p *pacer) waitDuration(t time.Time) time.Duration {
+// waitDuration returns how long to wait before sending the request logged at
+// t, and the index and rate of the phase it is scheduled in
+func (p *pacer) waitDuration(t time.Time) (time.Duration, int, float64) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -98,15 +186,17 @@ func (p *pacer) waitDuration(t time.Time) time.Duration {
 		p.lastRequestWallTime = now
 		p.phaseStartRequestTime = p.lastRequestTime
 		p.phaseStartWallTime = p.lastRequestWallTime
+		p.firstRequestWallTime = now
 	}
 
-	// Check if we have any phases left
+	// Check if we have any phases left, requests scheduled once the last
+	// phase elapsed belong to it
 	if len(p.phases) == 0 {
-		return 0
+		return 0, max(p.phaseIndex-1, 0), p.lastRate
 	}
 
 	originalDurationFromPhaseStart := t.Sub(p.phaseStartRequestTime)
-	expectedDurationFromPhaseStart := time.Duration(float64(originalDurationFromPhaseStart) / p.phases[0].rate)
+	expectedDurationFromPhaseStart := p.phases[0].wallOffset(originalDurationFromPhaseStart)
 	expectedWallTime := p.phaseStartWallTime.Add(expectedDurationFromPhaseStart)
 
 	p.reportStats(now, expectedWallTime)
@@ -114,7 +204,28 @@ func (p *pacer) waitDuration(t time.Time) time.Duration {
 	duration := expectedWallTime.Sub(now)
 	p.lastRequestTime = t
 	p.lastRequestWallTime = expectedWallTime
-	return duration
+	p.scheduledRequests++
+	return duration, p.phaseIndex, p.phases[0].rateAt(expectedDurationFromPhaseStart)
+}
+
+// expectedRPS is the average rate at which requests were scheduled to be sent
+func (p *pacer) expectedRPS() float64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	span := p.lastRequestWallTime.Sub(p.firstRequestWallTime)
+	if span <= 0 {
+		return 0
+	}
+	return float64(p.scheduledRequests) / span.Seconds()
+}
+
+// phaseElapsed returns the wall time elapsed in the current phase at now
+func (p *pacer) phaseElapsed(now time.Time) time.Duration {
+	if p.phaseStartWallTime.IsZero() {
+		return 0
+	}
+	return now.Sub(p.phaseStartWallTime)
 }
 
 func (p *pacer) isDone() bool {
@@ -131,11 +242,11 @@ func (p *pacer) reportStats(now, expectedWallTime time.Time) {
 	// Get current rate safely
 	var currentRate = 1.0
 	if len(p.phases) > 0 {
-		currentRate = p.phases[0].rate
+		currentRate = p.phases[0].rateAt(p.phaseElapsed(expectedWallTime))
 	}
 
 	for p.nextReport.Before(expectedWallTime) {
-		fmt.Fprintf(os.Stderr, "report_time=%s skew_seconds=%f last_request_time=%s rate=%f expected_rps=%d\n",
+		fmt.Fprintf(p.out, "report_time=%s skew_seconds=%f last_request_time=%s rate=%f expected_rps=%d\n",
 			p.nextReport.Format(time.RFC3339),
 			now.Sub(p.nextReport).Seconds(),
 			p.lastRequestTime.Format(time.RFC3339),
@@ -148,27 +259,76 @@ func (p *pacer) reportStats(now, expectedWallTime time.Time) {
 }
 
 // Format is [duration]@[rate] [duration]@[rate]..."
-// e.g. "5s@1 10m@2"
+// e.g. "5s@1 10m@2". Ramp phases go from one rate to another over their
+// duration, linearly with [duration]@[rate]->[rate], e.g. "5m@1->10", or
+// exponentially with [duration]@[rate]~exp~[rate], e.g. "10m@2~exp~20".
 func parsePhases(phasesStr string) ([]*phase, error) {
 	var phases []*phase
 
 	for _, durationAtRate := range strings.Split(phasesStr, " ") {
 		tokens := strings.Split(durationAtRate, "@")
 
+		if len(tokens) != 2 {
+			return nil, fmt.Errorf("invalid phase: %s", durationAtRate)
+		}
+
 		duration, err := time.ParseDuration(tokens[0])
 
 		if err != nil {
 			return nil, err
 		}
 
+		if start, end, ok := strings.Cut(tokens[1], "->"); ok {
+			ramp, err := parseRamp(durationAtRate, duration, rampLinear, start, end)
+			if err != nil {
+				return nil, err
+			}
+			phases = append(phases, ramp)
+			continue
+		}
+
+		if start, end, ok := strings.Cut(tokens[1], "~exp~"); ok {
+			ramp, err := parseRamp(durationAtRate, duration, rampExponential, start, end)
+			if err != nil {
+				return nil, err
+			}
+			phases = append(phases, ramp)
+			continue
+		}
+
 		rate, err := strconv.ParseFloat(tokens[1], 64)
 
 		if err != nil {
 			return nil, err
 		}
 
-		phases = append(phases, &phase{duration, rate})
+		phases = append(phases, &phase{duration: duration, rate: rate})
 	}
 
 	return phases, nil
 }
+
+func parseRamp(durationAtRate string, duration time.Duration, ramp rampKind, start, end string) (*phase, error) {
+	rate, err := strconv.ParseFloat(start, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	endRate, err := strconv.ParseFloat(end, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	if duration <= 0 {
+		return nil, fmt.Errorf("invalid phase %s: ramps need a positive duration", durationAtRate)
+	}
+
+	switch {
+	case ramp == rampExponential && (rate <= 0 || endRate <= 0):
+		return nil, fmt.Errorf("invalid phase %s: exponential ramps need positive rates", durationAtRate)
+	case rate < 0 || endRate < 0 || rate+endRate == 0:
+		return nil, fmt.Errorf("invalid phase %s: linear ramps need positive rates, one of them may be 0", durationAtRate)
+	}
+
+	return &phase{duration: duration, rate: rate, endRate: endRate, ramp: ramp}, nil
+}
@@ -53,9 +53,9 @@ func TestParseManyPhases(t *testing.T) {
 	}
 
 	expectedPhases := []*phase{
-		{5 * time.Minute, 2.5},
-		{20 * time.Minute, 5.0},
-		{time.Hour + 30*time.Minute, 10.0}}
+		{duration: 5 * time.Minute, rate: 2.5},
+		{duration: 20 * time.Minute, rate: 5.0},
+		{duration: time.Hour + 30*time.Minute, rate: 10.0}}
 
 	if len(actualPhases) != len(expectedPhases) {
 		t.Errorf("len(actualPhases) = %v; want 3", len(expectedPhases))
@@ -82,14 +82,14 @@ func TestWaitDuration(t *testing.T) {
 	}
 
 	now := time.Now()
-	duration := pacer.waitDuration(now)
+	duration, _, _ := pacer.waitDuration(now)
 
 	if duration > 0 {
 		t.Errorf("duration = %v; want 0 or negative", duration)
 	}
 
 	now = now.Add(2 * time.Second)
-	duration = pacer.waitDuration(now)
+	duration, _, _ = pacer.waitDuration(now)
 	expected := 2 * time.Second
 
 	if !equalsWithinThreshold(duration, expected, 100*time.Microsecond) {
@@ -105,14 +105,14 @@ func TestWaitDuration10X(t *testing.T) {
 	}
 
 	now := time.Now()
-	duration := pacer.waitDuration(now)
+	duration, _, _ := pacer.waitDuration(now)
 
 	if duration > 0 {
 		t.Errorf("duration = %v; want 0 or negative", duration)
 	}
 
 	now = now.Add(1 * time.Second)
-	duration = pacer.waitDuration(now)
+	duration, _, _ = pacer.waitDuration(now)
 	expected := time.Second / 10
 
 	if !equalsWithinThreshold(duration, expected, 100*time.Microsecond) {
@@ -138,6 +138,140 @@ func TestPacerDoneOnLastPhaseElapsed(t *testing.T) {
 	}
 }
 
+func TestWaitDurationPhase(t *testing.T) {
+	pacer, err := newPacer("30s@1 30s@5")
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	now := time.Now()
+
+	if _, index, rate := pacer.waitDuration(now); index != 0 || rate != 1 {
+		t.Errorf("pacer.waitDuration() phase = %v, %v; want 0, 1", index, rate)
+	}
+
+	pacer.onPhaseElapsed()
+
+	if _, index, rate := pacer.waitDuration(now.Add(time.Second)); index != 1 || rate != 5 {
+		t.Errorf("pacer.waitDuration() phase = %v, %v; want 1, 5", index, rate)
+	}
+
+	// Requests scheduled as the last phase elapses belong to it
+	pacer.onPhaseElapsed()
+
+	if _, index, rate := pacer.waitDuration(now.Add(2 * time.Second)); index != 1 || rate != 5 {
+		t.Errorf("pacer.waitDuration() phase after the last one elapsed = %v, %v; want 1, 5", index, rate)
+	}
+}
+
 func equalsWithinThreshold(d1, d2, threshold time.Duration) bool {
 	return math.Abs(float64(d1-d2)) <= float64(threshold)
 }
+
+func TestParseRampPhases(t *testing.T) {
+	phases, err := parsePhases("5m@1->10 10m@2~exp~20 1m@5->0")
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedPhases := []phase{
+		{duration: 5 * time.Minute, rate: 1, endRate: 10, ramp: rampLinear},
+		{duration: 10 * time.Minute, rate: 2, endRate: 20, ramp: rampExponential},
+		{duration: time.Minute, rate: 5, endRate: 0, ramp: rampLinear},
+	}
+
+	if len(phases) != len(expectedPhases) {
+		t.Fatalf("len(phases) = %v; want %v", len(phases), len(expectedPhases))
+	}
+
+	for i, expected := range expectedPhases {
+		if *phases[i] != expected {
+			t.Errorf("phases[%d] = %+v; want %+v", i, *phases[i], expected)
+		}
+	}
+}
+
+func TestParseInvalidRampPhases(t *testing.T) {
+	for _, pace := range []string{"5m@1->", "5m@->10", "0s@1->10", "5m@0->0", "5m@-1->10", "5m@0~exp~10", "5m@2~exp~x"} {
+		if _, err := parsePhases(pace); err == nil {
+			t.Errorf("parsePhases(%q) err = nil; want error", pace)
+		}
+	}
+}
+
+func TestRampRateAt(t *testing.T) {
+	linear := &phase{duration: 10 * time.Second, rate: 1, endRate: 11, ramp: rampLinear}
+	exponential := &phase{duration: 10 * time.Second, rate: 2, endRate: 200, ramp: rampExponential}
+
+	tests := []struct {
+		phase   *phase
+		elapsed time.Duration
+		want    float64
+	}{
+		{linear, 0, 1},
+		{linear, 5 * time.Second, 6},
+		{linear, 20 * time.Second, 11},
+		{exponential, 5 * time.Second, 20},
+		{exponential, 10 * time.Second, 200},
+	}
+
+	for _, test := range tests {
+		if got := test.phase.rateAt(test.elapsed); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("rateAt(%s) = %v; want %v", test.elapsed, got, test.want)
+		}
+	}
+}
+
+func TestRampWallOffset(t *testing.T) {
+	e := math.E
+
+	tests := []struct {
+		phase  *phase
+		offset time.Duration
+		want   time.Duration
+	}{
+		// The rate goes from 1 to 3 over 10s, so 20s of log time take 10s
+		{&phase{duration: 10 * time.Second, rate: 1, endRate: 3, ramp: rampLinear}, 20 * time.Second, 10 * time.Second},
+		// Halfway through, at rate 2, 7.5s of log time were replayed
+		{&phase{duration: 10 * time.Second, rate: 1, endRate: 3, ramp: rampLinear}, 7500 * time.Millisecond, 5 * time.Second},
+		// Past the end of the ramp the end rate holds
+		{&phase{duration: 10 * time.Second, rate: 1, endRate: 3, ramp: rampLinear}, 26 * time.Second, 12 * time.Second},
+		// Ramping down
+		{&phase{duration: 10 * time.Second, rate: 3, endRate: 1, ramp: rampLinear}, 12500 * time.Millisecond, 5 * time.Second},
+		// Ramping up from 0
+		{&phase{duration: 10 * time.Second, rate: 0, endRate: 2, ramp: rampLinear}, 2500 * time.Millisecond, 5 * time.Second},
+		// Ramping down to 0, nothing more is sent in the phase
+		{&phase{duration: 10 * time.Second, rate: 2, endRate: 0, ramp: rampLinear}, 20 * time.Second, 10 * time.Second},
+		// The rate grows by e every 10s, so (e-1)*10s of log time take 10s
+		{&phase{duration: 10 * time.Second, rate: 1, endRate: e, ramp: rampExponential}, time.Duration((e - 1) * float64(10*time.Second)), 10 * time.Second},
+		{&phase{duration: 10 * time.Second, rate: 1, endRate: e, ramp: rampExponential}, time.Duration((math.Sqrt(e) - 1) * float64(10*time.Second)), 5 * time.Second},
+		{&phase{duration: 10 * time.Second, rate: 2, endRate: 2, ramp: rampExponential}, 10 * time.Second, 5 * time.Second},
+	}
+
+	for i, test := range tests {
+		if got := test.phase.wallOffset(test.offset); !equalsWithinThreshold(got, test.want, time.Microsecond) {
+			t.Errorf("tests[%d]: wallOffset(%s) = %s; want %s", i, test.offset, got, test.want)
+		}
+	}
+}
+
+func TestWaitDurationRamp(t *testing.T) {
+	pacer, err := newPacer("10s@1->3")
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	pacer.waitDuration(now)
+
+	// Due 5s after the first request, when the rate reached 2
+	duration, _, _ := pacer.waitDuration(now.Add(7500 * time.Millisecond))
+	expected := 5 * time.Second
+
+	if !equalsWithinThreshold(duration, expected, 10*time.Millisecond) {
+		t.Errorf("duration = %v; want %v", duration, expected)
+	}
+}
@@ -19,17 +19,210 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package ripley
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"runtime"
+	"strings"
 	"sync"
 	"time"
 )
 
-func Replay(phasesStr string, silent, dryRun bool, timeout int, strict bool, numWorkers, connections, maxConnections int, disableKeepAlives bool, printStatsInterval time.Duration, metricsServerEnable bool, metricsServerAddr string) int {
-	// Default exit code
-	var exitCode = 0
+// ErrInvalidInput is returned by Run when some of the input could not be
+// parsed as requests and strict mode is off
+var ErrInvalidInput = errors.New("invalid input")
+
+// SLOViolationError is returned by Run when any of the SLOs was violated
+type SLOViolationError struct {
+	Violations []string
+}
+
+func (e *SLOViolationError) Error() string {
+	return strings.Join(e.Violations, "\n")
+}
+
+// AbortedError is returned by Run when the circuit breaker aborted the run
+type AbortedError struct {
+	Reason string
+}
+
+func (e *AbortedError) Error() string {
+	return e.Reason
+}
+
+// Config holds the configuration of a replay run
+type Config struct {
+	// Rate phases in [duration]@[rate] format, e.g. "1m@1 30s@1.5 1h@2", or
+	// ramps such as "5m@1->10" (linear) and "10m@2~exp~20" (exponential)
+	Pace string
+	// Consume input but do not send HTTP requests to targets
+	DryRun bool
+	// HTTP client timeout
+	Timeout time.Duration
+	// Stop the run on the first invalid request instead of reporting it as a result
+	Strict bool
+	// Number of HTTP client workers
+	Workers int
+	// Open model: when all workers are busy, start another one rather than
+	// delay the request, up to this many requests in flight. The closed model,
+	// which only uses Workers, is used when 0.
+	MaxInFlight int
+	// In the open model, drop requests once MaxInFlight requests are in flight
+	// instead of delaying them
+	DropWhenFull bool
+	// Grow and shrink the pool of workers between bounds as the run goes
+	Autoscale AutoscaleConfig
+	// Max open idle connections per target host
+	Connections int
+	// Max connections per target host, 0 is unlimited
+	MaxConnections int
+	// Force a new connection per request
+	DisableKeepAlives bool
+	// How long in-flight requests may take to complete once a run is interrupted
+	// or aborted before they are cancelled, 0 waits for them up to Timeout
+	DrainTimeout time.Duration
+	// Pacer statistics report interval, reporting is off when 0 or negative
+	StatsInterval time.Duration
+	// Pacer statistics are written here, defaults to os.Stderr
+	StatsOutput io.Writer
+	Metrics     MetricsConfig
+	// Space separated SLOs evaluated against the summary at the end of the run,
+	// e.g. "p99<300ms error_rate<0.5%"
+	SLOs           string
+	CircuitBreaker CircuitBreakerConfig
+	// Checked against the responses of requests without assertions of their own
+	Assertions *Assertions
+	// Bodies of responses failing their assertions are saved here when set
+	FailedBodiesDir string
+	// Send every request to a baseline and a candidate target and compare the responses
+	Shadow ShadowConfig
+	// Selects the requests read from the source that are replayed
+	Filter Filter
+	// Applied to every request read from the source before it is replayed
+	Rules *Rules
+	// Replaces the credentials of every request with a fresh token
+	Auth AuthConfig
+	// Render the URL, header values and body of requests as templates, e.g.
+	// "/users/{{randInt 1 100}}", before sending them
+	Templates bool
+}
+
+// DefaultConfig returns the configuration used by the ripley command by default
+func DefaultConfig() Config {
+	return Config{
+		Pace:         "10s@1",
+		Timeout:      10 * time.Second,
+		Workers:      runtime.NumCPU() * 2,
+		Connections:  10000,
+		DrainTimeout: 5 * time.Second,
+		Metrics: MetricsConfig{
+			Address: "0.0.0.0:8081",
+		},
+		CircuitBreaker: CircuitBreakerConfig{
+			Window:      30 * time.Second,
+			For:         10 * time.Second,
+			MinRequests: 10,
+		},
+		Auth: AuthConfig{
+			RefreshBefore: 30 * time.Second,
+		},
+		Autoscale: AutoscaleConfig{
+			Interval: time.Second,
+			MaxSkew:  10 * time.Millisecond,
+		},
+	}
+}
+
+// Replayer replays requests at multiples of their original rate
+type Replayer struct {
+	config Config
+	slos   []*condition
+}
+
+// New validates the configuration and returns a Replayer
+func New(config Config) (*Replayer, error) {
+	if _, err := parsePhases(config.Pace); err != nil {
+		return nil, fmt.Errorf("invalid pace %q: %w", config.Pace, err)
+	}
+
+	if config.Workers <= 0 {
+		return nil, fmt.Errorf("workers must be positive: %d", config.Workers)
+	}
+
+	if config.MaxInFlight != 0 && config.MaxInFlight < config.Workers {
+		return nil, fmt.Errorf("max in-flight requests must be 0 or at least the number of workers: %d", config.MaxInFlight)
+	}
+
+	if _, err := newAutoscaler(config.Autoscale); err != nil {
+		return nil, err
+	}
+
+	slos, err := parseConditions(config.SLOs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := newCircuitBreaker(config.CircuitBreaker); err != nil {
+		return nil, err
+	}
+
+	if _, err := newShadowDiffer(config.Shadow); err != nil {
+		return nil, err
+	}
+
+	if _, err := newAuthenticator(config.Auth); err != nil {
+		return nil, err
+	}
+
+	if _, err := newRequestFilter(config.Filter); err != nil {
+		return nil, err
+	}
+
+	if _, err := newTransformer(config.Rules); err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+
+	if config.StatsOutput == nil {
+		config.StatsOutput = os.Stderr
+	}
+
+	if config.Assertions != nil {
+		assertions := *config.Assertions
+		assertions.JSONPath = maps.Clone(assertions.JSONPath)
+		if err := assertions.compile(); err != nil {
+			return nil, fmt.Errorf("invalid assertions: %w", err)
+		}
+		config.Assertions = &assertions
+	}
+
+	return &Replayer{config: config, slos: slos}, nil
+}
+
+// sourceItem is a request or error read from a RequestSource
+type sourceItem struct {
+	req *Request
+	err error
+}
+
+// Run replays the requests from source, sending each result to sink, until
+// the source is exhausted, the last pace phase elapses or ctx is cancelled.
+// The summary is returned even when the run fails its SLOs, is aborted or
+// interrupted, in which case the error is an *SLOViolationError, an
+// *AbortedError or the context's error, joined with ErrInvalidInput when some
+// input was invalid.
+func (r *Replayer) Run(ctx context.Context, source RequestSource, sink ResultSink) (*Summary, error) {
+	// Cancelled to stop sending requests early
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	// Cancelled to give up on in-flight requests once the drain timeout elapses.
+	// Not derived from ctx, so in-flight requests can complete after an interrupt.
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
 	// Ensures we have handled all HTTP Request results before exiting
 	var waitGroup sync.WaitGroup
 	// Ensures result handler goroutine completes before closing results channel
@@ -41,27 +234,104 @@ func Replay(phasesStr string, silent, dryRun bool, timeout int, strict bool, num
 	// HTTP client workers will send their results on this channel
 	results := make(chan *Result)
 
+	// The autoscaler resizes the worker pool, which starts with Workers within its bounds
+	scaler, err := newAutoscaler(r.config.Autoscale)
+	if err != nil {
+		return nil, err
+	}
+	workers := r.config.Workers
+	if scaler != nil {
+		workers = scaler.initialSize(workers)
+	}
+
 	// Initialize metrics recorder (no-op if disabled)
-	metricsRecorder := NewMetricsRecorder(MetricsConfig{
-		Enabled: metricsServerEnable,
-		Address: metricsServerAddr,
-	}, numWorkers)
+	metricsRecorder := NewMetricsRecorder(r.config.Metrics, workers)
 	stopMonitoring := metricsRecorder.StartMonitoring(requests, results)
 	defer stopMonitoring()
 
 	// The pacer controls the rate of replay
-	pacer, err := newPacer(phasesStr)
-	pacer.ReportInterval = printStatsInterval
+	pacer, err := newPacer(r.config.Pace)
+	if err != nil {
+		return nil, err
+	}
+	pacer.ReportInterval = r.config.StatsInterval
+	pacer.out = r.config.StatsOutput
+
+	// The circuit breaker stops the run early when the target is clearly failing
+	breaker, err := newCircuitBreaker(r.config.CircuitBreaker)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.config.FailedBodiesDir != "" {
+		if err := os.MkdirAll(r.config.FailedBodiesDir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	checker := &responseChecker{assertions: r.config.Assertions, bodiesDir: r.config.FailedBodiesDir}
 
+	// In shadow mode each request goes to both the baseline and the candidate
+	shadow, err := newShadowDiffer(r.config.Shadow)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	// Read Request JSONL input from STDIN
-	scanner := bufio.NewScanner(bufio.NewReaderSize(os.Stdin, 32*1024*1024))
+	// The filter skips requests before they are rewritten and paced
+	filter, err := newRequestFilter(r.config.Filter)
+	if err != nil {
+		return nil, err
+	}
+	var filtered *filterSource
+	if filter != nil {
+		filtered = &filterSource{source: source, filter: filter}
+		source = filtered
+	}
+
+	// Rules rewrite requests as they are read from the source
+	transformer, err := newTransformer(r.config.Rules)
+	if err != nil {
+		return nil, err
+	}
+	if transformer != nil {
+		source = &transformSource{source: source, transformer: transformer}
+	}
+
+	// Tokens are refreshed in the background until in-flight requests are done
+	auth, err := newAuthenticator(r.config.Auth)
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil && !r.config.DryRun {
+		// Only refreshes outlive an interrupt, the first token must come before the run starts
+		if err := auth.start(runCtx, requestCtx); err != nil {
+			return nil, err
+		}
+	}
+
+	var templates *templater
+	if r.config.Templates {
+		templates = newTemplater()
+	}
+
+	// Aggregated results for the summary, only touched by the result handler
+	stats := newSummaryCollector()
+	var sinkErr error
+	runStart := time.Now()
 
 	// Start HTTP client goroutine pool
-	startClientWorkers(numWorkers, requests, results, dryRun, timeout, connections, maxConnections, disableKeepAlives)
+	pool := newWorkerPool(requestCtx, r.config, requests, results, checker, shadow, auth, templates, metricsRecorder)
+	pool.start(workers)
+	if scaler != nil {
+		go scaler.run(runCtx, pool)
+	}
+
+	// The dispatcher hands requests to idle workers, or starts new ones in the open model
+	dispatcher := &dispatcher{
+		requests:     requests,
+		pool:         pool,
+		maxInFlight:  int64(r.config.MaxInFlight),
+		dropWhenFull: r.config.DropWhenFull,
+	}
 	pacer.start()
 
 	// Goroutine to handle the  HTTP client result
@@ -76,53 +346,129 @@ func Replay(phasesStr string, silent, dryRun bool, timeout int, strict bool, num
 				return
 			}
 
-			metricsRecorder.RecordRequest(result)
+			// Invalid requests were never sent, so they only go to the sink
+			if !result.invalid {
+				dispatcher.done()
+				metricsRecorder.RecordRequest(result)
+				stats.record(result)
+				breaker.record(result)
+
+				if breaker.isTripped() {
+					stop()
+				}
+			}
+
+			if sinkErr == nil {
+				if sinkErr = sink.Handle(result); sinkErr != nil {
+					stop()
+				}
+			}
+		}
+	}()
 
-			if !silent {
-				jsonResult, err := json.Marshal(result)
+	// Read the source in its own goroutine, so a source blocked on input
+	// does not stop the run from being interrupted
+	incoming := make(chan sourceItem)
+	go func() {
+		for {
+			req, err := source.Next()
 
-				if err != nil {
-					panic(err)
+			// Requests not read as JSON, e.g. from NewSliceSource, have their
+			// assertions compiled here, before any worker uses them
+			if err == nil && req.Assertions != nil && !req.Assertions.compiled {
+				if compileErr := req.Assertions.compile(); compileErr != nil {
+					err = &InvalidRequestError{Request: req, Err: fmt.Errorf("invalid assertions: %w", compileErr)}
 				}
+			}
 
-				fmt.Println(string(jsonResult))
+			select {
+			case incoming <- sourceItem{req, err}:
+			case <-runCtx.Done():
+				return
+			}
+
+			if err != nil && !errors.As(err, new(*InvalidRequestError)) {
+				return
 			}
 		}
 	}()
 
-	for scanner.Scan() {
-		req, err := unmarshalRequest(scanner.Bytes())
-		if err != nil {
-			exitCode = 126
-			result, _ := json.Marshal(Result{
-				StatusCode: 0,
-				Latency:    0,
-				Request:    req,
-				ErrorMsg:   fmt.Sprintf("%v", err),
-			})
-			fmt.Println(string(result))
-
-			if strict {
-				panic(err)
+	invalidRequests := 0
+	var sourceErr error
+
+loop:
+	for {
+		var item sourceItem
+
+		select {
+		case item = <-incoming:
+		case <-runCtx.Done():
+			break loop
+		}
+
+		if item.err == io.EOF {
+			break loop
+		}
+
+		var invalid *InvalidRequestError
+		if errors.As(item.err, &invalid) {
+			invalidRequests++
+
+			if r.config.Strict {
+				sourceErr = item.err
+				break loop
 			}
+
+			waitGroup.Add(1)
+			results <- &Result{Request: invalid.Request, ErrorMsg: invalid.Error(), invalid: true}
 			continue
 		}
 
+		if item.err != nil {
+			sourceErr = item.err
+			break loop
+		}
+
+		req := item.req
+
 		if pacer.isDone() {
-			break
+			break loop
 		}
 
 		// The pacer decides how long to wait between requests
-		waitDuration := pacer.waitDuration(req.Timestamp)
-		time.Sleep(waitDuration)
+		// The phase is the one the request is scheduled in, even if it elapses during the wait
+		waitDuration, phase, rate := pacer.waitDuration(req.Timestamp)
+		req.scheduled = time.Now().Add(waitDuration)
+		timer := time.NewTimer(waitDuration)
+
+		select {
+		case <-timer.C:
+		case <-runCtx.Done():
+			timer.Stop()
+			break loop
+		}
+
+		req.phase, req.rate = phase, rate
 		waitGroup.Add(1)
-		requests <- req
+
+		if !dispatcher.dispatch(runCtx, req) {
+			waitGroup.Done()
+
+			if runCtx.Err() != nil {
+				break loop
+			}
+		}
 	}
 
-	if scanner.Err() != nil {
-		panic(scanner.Err())
+	// Bound how long in-flight requests may take when the run was stopped early
+	if runCtx.Err() != nil && r.config.DrainTimeout > 0 {
+		drainTimer := time.AfterFunc(r.config.DrainTimeout, cancelRequests)
+		defer drainTimer.Stop()
 	}
 
+	// Stop the source goroutine
+	stop()
+
 	// Close requests channel to signal worker goroutines to stop
 	close(requests)
 
@@ -135,5 +481,39 @@ func Replay(phasesStr string, silent, dryRun bool, timeout int, strict bool, num
 	// Wait for result handler to finish processing all results
 	resultHandlerWG.Wait()
 
-	return exitCode
+	summary := stats.summary(time.Since(runStart), pacer.expectedRPS())
+	summary.InvalidRequests = invalidRequests
+	summary.DelayedRequests = dispatcher.delayed
+	summary.DroppedRequests = dispatcher.dropped
+	if filtered != nil {
+		summary.FilteredRequests = int(filtered.filtered.Load())
+	}
+
+	if sourceErr != nil {
+		return summary, sourceErr
+	}
+
+	if sinkErr != nil {
+		return summary, sinkErr
+	}
+
+	var errs []error
+
+	if breaker.isTripped() {
+		errs = append(errs, &AbortedError{Reason: breaker.reason})
+	}
+
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
+	}
+
+	if violations := evaluateSLOs(r.slos, summary); len(violations) > 0 {
+		errs = append(errs, &SLOViolationError{Violations: violations})
+	}
+
+	if invalidRequests > 0 {
+		errs = append(errs, ErrInvalidInput)
+	}
+
+	return summary, errors.Join(errs...)
 }
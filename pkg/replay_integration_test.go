@@ -20,9 +20,13 @@ package ripley
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -44,50 +48,19 @@ func TestReplayRaceConditionTermination(t *testing.T) {
 	// Create test requests using the test server URL
 	testRequests := createTestRequests(server.URL, 50)
 
-	// Save original stdin
-	originalStdin := os.Stdin
-
 	// Run multiple iterations to increase chance of reproducing the race condition
 	for i := 0; i < 100; i++ {
 		t.Run("iteration", func(t *testing.T) {
 			// Reset request count
 			atomic.StoreInt64(&requestCount, 0)
 
-			// Create a pipe to simulate stdin
-			r, w, err := os.Pipe()
-			if err != nil {
-				t.Fatalf("Failed to create pipe: %v", err)
-			}
-
-			// Replace stdin
-			os.Stdin = r
-
-			// Write test data to pipe in a goroutine
-			go func() {
-				defer func() { _ = w.Close() }()
-				_, _ = w.Write([]byte(testRequests))
-			}()
-
-			// Capture stdout to suppress output during test
-			originalStdout := os.Stdout
-			_, captureWriter, _ := os.Pipe()
-			os.Stdout = captureWriter
-
-			// Run the replay function with a short phase duration to complete quickly
+			// Run the replay with a short phase duration to complete quickly
 			// Use high worker count and connections to increase goroutine concurrency
-			exitCode := Replay("100ms@10", true, false, 1, false, 20, 100, 0, false, 0, false, "")
-
-			// Restore stdout
-			os.Stdout = originalStdout
-			_ = captureWriter.Close()
-
-			// Restore stdin
-			os.Stdin = originalStdin
-			_ = r.Close()
+			_, err := runReplay(t, testConfig("100ms@10", time.Second, 20, 100), testRequests)
 
-			// Verify that the function completed successfully
-			if exitCode != 0 {
-				t.Errorf("Expected exit code 0, got %d", exitCode)
+			// Verify that the run completed successfully
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
 			}
 
 			// Add a small delay to let any lingering goroutines finish
@@ -102,7 +75,7 @@ func TestReplayRaceConditionWithSlowServer(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		wg.Add(1)
 		defer wg.Done()
-		// Longer delay to ensure requests are still processing when Replay tries to exit
+		// Longer delay to ensure requests are still processing when the run tries to exit
 		time.Sleep(200 * time.Millisecond)
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("OK"))
@@ -111,43 +84,15 @@ func TestReplayRaceConditionWithSlowServer(t *testing.T) {
 
 	testRequests := createTestRequests(server.URL, 10)
 
-	// Save original stdin/stdout
-	originalStdin := os.Stdin
-	originalStdout := os.Stdout
-
 	for i := 0; i < 20; i++ {
 		t.Run("slow_server_iteration", func(t *testing.T) {
-			// Create pipe for stdin
-			r, w, err := os.Pipe()
-			if err != nil {
-				t.Fatalf("Failed to create pipe: %v", err)
-			}
-			defer func() { _ = r.Close() }()
-
-			os.Stdin = r
-
-			// Capture stdout
-			_, captureWriter, _ := os.Pipe()
-			os.Stdout = captureWriter
-
-			// Write test data
-			go func() {
-				defer func() { _ = w.Close() }()
-				_, _ = w.Write([]byte(testRequests))
-			}()
-
 			// Run with very short phase to trigger early completion attempt
 			start := time.Now()
-			exitCode := Replay("50ms@5", true, false, 1, false, 10, 50, 0, false, 0, false, "")
+			_, err := runReplay(t, testConfig("50ms@5", time.Second, 10, 50), testRequests)
 			duration := time.Since(start)
 
-			// Restore streams
-			os.Stdout = originalStdout
-			os.Stdin = originalStdin
-			_ = captureWriter.Close()
-
-			if exitCode != 0 {
-				t.Errorf("Expected exit code 0, got %d", exitCode)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
 			}
 
 			// The test should complete in reasonable time despite slow server
@@ -178,38 +123,16 @@ func TestReplayRaceConditionStressTest(t *testing.T) {
 	// Generate many requests
 	testRequests := createTestRequests(server.URL, 200)
 
-	originalStdin := os.Stdin
-	originalStdout := os.Stdout
-
 	// Run stress test iterations
 	for i := 0; i < 50; i++ {
 		t.Run("stress_iteration", func(t *testing.T) {
-			r, w, err := os.Pipe()
-			if err != nil {
-				t.Fatalf("Failed to create pipe: %v", err)
-			}
-			defer func() { _ = r.Close() }()
-
-			os.Stdin = r
-			_, captureWriter, _ := os.Pipe()
-			os.Stdout = captureWriter
-
-			go func() {
-				defer func() { _ = w.Close() }()
-				_, _ = w.Write([]byte(testRequests))
-			}()
-
 			// High concurrency settings to maximize race condition potential
 			start := time.Now()
-			exitCode := Replay("200ms@20", true, false, 2, false, 50, 200, 0, false, 0, false, "")
+			_, err := runReplay(t, testConfig("200ms@20", 2*time.Second, 50, 200), testRequests)
 			duration := time.Since(start)
 
-			os.Stdout = originalStdout
-			os.Stdin = originalStdin
-			_ = captureWriter.Close()
-
-			if exitCode != 0 {
-				t.Errorf("Expected exit code 0, got %d", exitCode)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
 			}
 
 			// Should not hang indefinitely
@@ -232,38 +155,606 @@ func TestReplayWithDisableKeepAlives(t *testing.T) {
 
 	testRequests := createTestRequests(server.URL, 10)
 
-	originalStdin := os.Stdin
-	originalStdout := os.Stdout
+	// Run with disable-keepalive enabled (use higher rate to complete faster)
+	config := testConfig("1s@20", time.Second, 10, 50)
+	config.DisableKeepAlives = true
+	_, err := runReplay(t, config, testRequests)
+
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if atomic.LoadInt64(&requestCount) != 10 {
+		t.Errorf("Expected 10 requests, got %d", requestCount)
+	}
+}
+
+func TestReplaySLOViolation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	testRequests := createTestRequests(server.URL, 5)
+
+	config := testConfig("1s@20", time.Second, 10, 50)
+	config.SLOs = "status_5xx<1 p99<10s"
+	summary, err := runReplay(t, config, testRequests)
+
+	var violation *SLOViolationError
+	if !errors.As(err, &violation) {
+		t.Fatalf("Expected SLOViolationError, got %v", err)
+	}
+
+	if len(violation.Violations) != 1 || violation.Violations[0] != "SLO status_5xx<1 violated: actual 5" {
+		t.Errorf("Unexpected violations: %q", violation.Violations)
+	}
+
+	if summary.StatusCodes["503"] != 5 {
+		t.Errorf("summary.StatusCodes = %v; want map[503:5]", summary.StatusCodes)
+	}
+}
+
+func TestReplayAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			_, _ = w.Write([]byte(`{"status": "degraded"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	input := createTestRequests(server.URL, 2) +
+		`{"url": "` + server.URL + `?fail=1", "method": "GET", "timestamp": "` + now.Add(300*time.Millisecond).Format(time.RFC3339Nano) + `"}` + "\n" +
+		`{"url": "` + server.URL + `?fail=1", "method": "GET", "timestamp": "` + now.Add(400*time.Millisecond).Format(time.RFC3339Nano) + `", "assertions": {"status": [200]}}` + "\n"
 
-	r, w, err := os.Pipe()
+	config := testConfig("1s@20", time.Second, 2, 10)
+	config.Assertions = &Assertions{Status: []int{200}, JSONPath: map[string]any{"$.status": "ok"}}
+	config.FailedBodiesDir = t.TempDir()
+	config.SLOs = "assertion_failures<1"
+
+	replayer, err := New(config)
 	if err != nil {
-		t.Fatalf("Failed to create pipe: %v", err)
+		t.Fatalf("Unexpected error: %v", err)
 	}
-	defer func() { _ = r.Close() }()
 
-	os.Stdin = r
-	_, captureWriter, _ := os.Pipe()
-	os.Stdout = captureWriter
+	var failures []*Result
+	sink := ResultSinkFunc(func(result *Result) error {
+		if result.AssertionFailure != "" {
+			failures = append(failures, result)
+		}
+		return nil
+	})
+
+	summary, err := replayer.Run(context.Background(), NewJSONLSource(strings.NewReader(input)), sink)
+
+	if !errors.As(err, new(*SLOViolationError)) {
+		t.Errorf("Expected SLOViolationError, got %v", err)
+	}
+
+	if summary.AssertionFailures != 1 || summary.Errors != 0 {
+		t.Errorf("summary = %d assertion failures and %d errors; want 1 and 0", summary.AssertionFailures, summary.Errors)
+	}
 
+	if len(failures) != 1 || failures[0].AssertionFailure != `$.status is "degraded", want "ok"` {
+		t.Fatalf("failures = %v; want a single $.status failure", failures)
+	}
+
+	body, err := os.ReadFile(failures[0].BodyFile)
+	if err != nil || string(body) != `{"status": "degraded"}` {
+		t.Errorf("saved body = %q, %v; want the response body", body, err)
+	}
+}
+
+func TestReplaySliceSourceAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id": 2}`))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	requests := []*Request{
+		{Method: "GET", Url: server.URL, Timestamp: now, Assertions: &Assertions{BodyMatches: `"id": 1`}},
+		{Method: "GET", Url: server.URL, Timestamp: now, Assertions: &Assertions{JSONPath: map[string]any{"$.id": 1}}},
+		{Method: "GET", Url: server.URL, Timestamp: now, Assertions: &Assertions{BodyMatches: `(`}},
+	}
+
+	replayer, err := New(testConfig("1s@1", time.Second, 1, 1))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var failures, invalid []string
+	sink := ResultSinkFunc(func(result *Result) error {
+		if result.AssertionFailure != "" {
+			failures = append(failures, result.AssertionFailure)
+		}
+		if result.invalid {
+			invalid = append(invalid, result.ErrorMsg)
+		}
+		return nil
+	})
+
+	_, err = replayer.Run(context.Background(), NewSliceSource(requests), sink)
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Run() err = %v; want ErrInvalidInput", err)
+	}
+
+	want := []string{`body does not match "\"id\": 1"`, "$.id is 2, want 1"}
+	if !slices.Equal(failures, want) {
+		t.Errorf("assertion failures = %q; want %q", failures, want)
+	}
+
+	if len(invalid) != 1 || !strings.HasPrefix(invalid[0], "invalid assertions") {
+		t.Errorf("invalid results = %q; want one with invalid assertions", invalid)
+	}
+}
+
+func TestReplayShadow(t *testing.T) {
+	baseline := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id": 1, "requestId": "a"}`))
+	}))
+	defer baseline.Close()
+
+	var candidateRequests atomic.Int32
+	candidate := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		candidateRequests.Add(1)
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = w.Write([]byte(`{"requestId": "b", "id": 1}`))
+	}))
+	defer candidate.Close()
+
+	now := time.Now()
+	input := createTestRequests("http://example.com/ok", 2) +
+		`{"url": "http://example.com/broken", "method": "GET", "timestamp": "` + now.Add(300*time.Millisecond).Format(time.RFC3339Nano) + `"}` + "\n"
+
+	config := testConfig("1s@20", time.Second, 2, 10)
+	config.Shadow = ShadowConfig{Baseline: baseline.URL, Candidate: candidate.URL, IgnoreFields: []string{"requestId"}}
+
+	summary, err := runReplay(t, config, input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if candidateRequests.Load() != 3 {
+		t.Errorf("candidate requests = %d; want 3", candidateRequests.Load())
+	}
+
+	if summary.Shadow == nil {
+		t.Fatalf("summary.Shadow = nil; want shadow summary")
+	}
+
+	if summary.Shadow.Compared != 3 || summary.Shadow.Diffs != 1 || summary.Shadow.DiffsByKind["status"] != 1 {
+		t.Errorf("summary.Shadow = %+v; want 1 status difference in 3", summary.Shadow)
+	}
+
+	if len(summary.Shadow.Examples) != 1 || summary.Shadow.Examples[0].Url != candidate.URL+"/broken" {
+		t.Errorf("summary.Shadow.Examples = %+v; want the /broken request", summary.Shadow.Examples)
+	}
+}
+
+func TestReplayAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	input := `{"url": "` + server.URL + `", "method": "GET", "timestamp": "` + time.Now().Format(time.RFC3339Nano) + `", "headers": {"authorization": "Bearer expired"}}` + "\n"
+
+	config := testConfig("1s@1", time.Second, 1, 1)
+	config.Auth.Source = &CommandToken{Command: "echo fresh"}
+
+	summary, err := runReplay(t, config, input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if summary.StatusCodes["200"] != 1 {
+		t.Errorf("summary.StatusCodes = %v; want a single 200", summary.StatusCodes)
+	}
+
+	config.Auth.Source = &CommandToken{Command: "exit 1"}
+	replayer, err := New(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := replayer.Run(context.Background(), NewJSONLSource(strings.NewReader(input)), DiscardResults); err == nil {
+		t.Errorf("Run() err = nil; want error obtaining the token")
+	}
+}
+
+func TestReplayAuthCancelledWhileObtainingToken(t *testing.T) {
+	// The token endpoint does not respond until the test is over
+	release := make(chan struct{})
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer tokenServer.Close()
+	defer close(release)
+
+	config := testConfig("1s@1", time.Second, 1, 1)
+	config.Auth.Source = &ClientCredentials{TokenURL: tokenServer.URL, ClientID: "ripley"}
+
+	replayer, err := New(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
 	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(testRequests))
+		_, err := replayer.Run(ctx, NewJSONLSource(strings.NewReader("")), DiscardResults)
+		done <- err
 	}()
 
-	// Run with disable-keepalive enabled (use higher rate to complete faster)
-	exitCode := Replay("1s@20", true, false, 1, false, 10, 50, 0, true, 0, false, "")
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Run() err = nil; want error obtaining the token")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run() did not return once ctx was done")
+	}
+}
+
+func TestReplayCorrectedLatency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
 
-	os.Stdout = originalStdout
-	os.Stdin = originalStdin
-	_ = captureWriter.Close()
+	// All requests are scheduled at once, but a single worker sends them one after another
+	timestamp := time.Now().Format(time.RFC3339Nano)
+	input := strings.Repeat(`{"url": "`+server.URL+`", "method": "GET", "timestamp": "`+timestamp+`"}`+"\n", 3)
 
-	if exitCode != 0 {
-		t.Errorf("Expected exit code 0, got %d", exitCode)
+	config := testConfig("10s@1", time.Second, 1, 1)
+	replayer, err := New(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
 	}
 
-	if atomic.LoadInt64(&requestCount) != 10 {
-		t.Errorf("Expected 10 requests, got %d", requestCount)
+	var results []*Result
+	sink := ResultSinkFunc(func(result *Result) error {
+		results = append(results, result)
+		return nil
+	})
+
+	summary, err := replayer.Run(context.Background(), NewJSONLSource(strings.NewReader(input)), sink)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if summary.DelayedRequests < 2 {
+		t.Errorf("summary.DelayedRequests = %d; want at least 2", summary.DelayedRequests)
+	}
+
+	if summary.Latency.Max >= 200*time.Millisecond || summary.CorrectedLatency.Max < 250*time.Millisecond {
+		t.Errorf("latency max = %s, corrected max = %s; want corrected latency to include the wait for the worker", summary.Latency.Max, summary.CorrectedLatency.Max)
+	}
+
+	for _, result := range results {
+		if result.SentAt.Before(result.ScheduledAt) || result.CorrectedLatency != result.Latency+result.SentAt.Sub(result.ScheduledAt) {
+			t.Errorf("result scheduled at %s, sent at %s with latency %s and corrected latency %s; want consistent times",
+				result.ScheduledAt, result.SentAt, result.Latency, result.CorrectedLatency)
+		}
+	}
+}
+
+func TestReplayOpenModel(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			previous := maxInFlight.Load()
+			if current <= previous || maxInFlight.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	timestamp := time.Now().Format(time.RFC3339Nano)
+	input := strings.Repeat(`{"url": "`+server.URL+`", "method": "GET", "timestamp": "`+timestamp+`"}`+"\n", 5)
+
+	config := testConfig("10s@1", time.Second, 1, 10)
+	config.MaxInFlight = 10
+
+	summary, err := runReplay(t, config, input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if summary.TotalRequests != 5 || summary.DelayedRequests != 0 || maxInFlight.Load() != 5 {
+		t.Errorf("summary = %d requests, %d delayed, %d in flight; want 5 requests sent at once", summary.TotalRequests, summary.DelayedRequests, maxInFlight.Load())
+	}
+
+	if summary.CorrectedLatency.Max >= 350*time.Millisecond {
+		t.Errorf("summary.CorrectedLatency.Max = %s; want requests sent on schedule", summary.CorrectedLatency.Max)
+	}
+
+	config.MaxInFlight = 2
+	config.DropWhenFull = true
+
+	summary, err = runReplay(t, config, input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if summary.TotalRequests != 2 || summary.DroppedRequests != 3 {
+		t.Errorf("summary = %d requests and %d dropped; want 2 and 3", summary.TotalRequests, summary.DroppedRequests)
+	}
+}
+
+func TestReplayAutoscale(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			previous := maxInFlight.Load()
+			if current <= previous || maxInFlight.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	timestamp := time.Now().Format(time.RFC3339Nano)
+	input := strings.Repeat(`{"url": "`+server.URL+`", "method": "GET", "timestamp": "`+timestamp+`"}`+"\n", 40)
+
+	config := testConfig("10s@1", time.Second, 1, 10)
+	config.Autoscale = AutoscaleConfig{MaxWorkers: 8, Interval: 20 * time.Millisecond, MaxSkew: 10 * time.Millisecond}
+
+	summary, err := runReplay(t, config, input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if summary.TotalRequests != 40 {
+		t.Errorf("summary.TotalRequests = %d; want 40", summary.TotalRequests)
+	}
+
+	if got := maxInFlight.Load(); got < 4 || got > 8 {
+		t.Errorf("max in-flight requests = %d; want the pool to grow up to 8 workers", got)
+	}
+}
+
+func TestReplayAbortsOnFailingTarget(t *testing.T) {
+	var requestCount int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requestCount, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	// 100 requests 100ms apart take 5s to replay at 2x
+	testRequests := createTestRequests(server.URL, 100)
+
+	config := testConfig("1m@2", time.Second, 10, 50)
+	config.CircuitBreaker = CircuitBreakerConfig{
+		Conditions:  "status_5xx_rate>50%",
+		Window:      time.Second,
+		For:         500 * time.Millisecond,
+		MinRequests: 5,
 	}
+
+	start := time.Now()
+	_, err := runReplay(t, config, testRequests)
+	duration := time.Since(start)
+
+	var aborted *AbortedError
+	if !errors.As(err, &aborted) {
+		t.Errorf("Expected AbortedError, got %v", err)
+	}
+
+	if duration > 3*time.Second {
+		t.Errorf("Replay took %v; want it aborted early", duration)
+	}
+
+	if atomic.LoadInt64(&requestCount) >= 100 {
+		t.Errorf("Expected fewer than 100 requests, got %d", requestCount)
+	}
+}
+
+func TestReplayInvalidInput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	testRequests := createTestRequests(server.URL, 2) + `{"method": "WHAT"}` + "\n" + createTestRequests(server.URL, 2)
+
+	var results []*Result
+	sink := ResultSinkFunc(func(result *Result) error {
+		results = append(results, result)
+		return nil
+	})
+
+	replayer, err := New(testConfig("1m@20", time.Second, 2, 10))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	summary, err := replayer.Run(context.Background(), NewJSONLSource(strings.NewReader(testRequests)), sink)
+
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+
+	if summary.InvalidRequests != 1 || summary.TotalRequests != 4 {
+		t.Errorf("summary = %d invalid, %d total; want 1 invalid, 4 total", summary.InvalidRequests, summary.TotalRequests)
+	}
+
+	if len(results) != 5 {
+		t.Errorf("len(results) = %d; want 5", len(results))
+	}
+}
+
+func TestReplayStrictStopsOnInvalidInput(t *testing.T) {
+	config := testConfig("1m@20", time.Second, 2, 10)
+	config.Strict = true
+	config.DryRun = true
+
+	_, err := runReplay(t, config, `{"method": "WHAT"}`+"\n")
+
+	var invalid *InvalidRequestError
+	if !errors.As(err, &invalid) || invalid.Error() != "invalid method: WHAT" {
+		t.Errorf("Expected InvalidRequestError, got %v", err)
+	}
+}
+
+func TestReplayContextCancelled(t *testing.T) {
+	config := testConfig("1m@1", time.Second, 2, 10)
+	config.DryRun = true
+
+	replayer, err := New(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 10 requests one second apart take 10s to replay at 1x
+	var requests []*Request
+	baseTime := time.Now()
+	for i := 0; i < 10; i++ {
+		requests = append(requests, &Request{Method: "GET", Url: "http://localhost/", Timestamp: baseTime.Add(time.Duration(i) * time.Second)})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	summary, err := replayer.Run(ctx, NewSliceSource(requests), DiscardResults)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if time.Since(start) > time.Second {
+		t.Errorf("Run took %v; want it to stop when the context is done", time.Since(start))
+	}
+
+	if summary.TotalRequests != 1 {
+		t.Errorf("summary.TotalRequests = %d; want 1", summary.TotalRequests)
+	}
+}
+
+func TestReplayInterruptedDrainsInFlightRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	config := testConfig("1m@1", 10*time.Second, 2, 10)
+	config.DrainTimeout = 200 * time.Millisecond
+
+	replayer, err := New(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var results []*Result
+	sink := ResultSinkFunc(func(result *Result) error {
+		results = append(results, result)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// Requests are 100ms apart, so only the first one is in-flight
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	summary, err := replayer.Run(ctx, NewJSONLSource(strings.NewReader(createTestRequests(server.URL, 10))), sink)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("Run took %v; want in-flight requests cancelled after the drain timeout", time.Since(start))
+	}
+
+	if summary.TotalRequests != 1 || summary.Errors != 1 {
+		t.Errorf("summary = %d total, %d errors; want 1 total, 1 error", summary.TotalRequests, summary.Errors)
+	}
+
+	if len(results) != 1 || !strings.Contains(results[0].ErrorMsg, "context canceled") {
+		t.Errorf("results = %+v; want 1 cancelled request", results)
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	invalidSLOs := testConfig("10s@1", time.Second, 1, 1)
+	invalidSLOs.SLOs = "p99"
+	invalidAssertions := testConfig("10s@1", time.Second, 1, 1)
+	invalidAssertions.Assertions = &Assertions{BodyMatches: "("}
+	invalidShadow := testConfig("10s@1", time.Second, 1, 1)
+	invalidShadow.Shadow = ShadowConfig{Baseline: "localhost:8080"}
+	invalidRules := testConfig("10s@1", time.Second, 1, 1)
+	invalidRules.Rules = &Rules{Rules: []Rule{{Path: &Rewrite{Pattern: "("}}}}
+	invalidFilter := testConfig("10s@1", time.Second, 1, 1)
+	invalidFilter.Filter = Filter{SamplePercent: 200}
+	invalidMaxInFlight := testConfig("10s@1", time.Second, 4, 1)
+	invalidMaxInFlight.MaxInFlight = 2
+	invalidAutoscale := testConfig("10s@1", time.Second, 1, 1)
+	invalidAutoscale.Autoscale.MinWorkers = 4
+	invalidAutoscale.Autoscale.MaxWorkers = 2
+
+	configs := []Config{
+		testConfig("10s", time.Second, 1, 1),
+		testConfig("10s@1", time.Second, 0, 1),
+		invalidSLOs,
+		invalidAssertions,
+		invalidShadow,
+		invalidRules,
+		invalidFilter,
+		invalidMaxInFlight,
+		invalidAutoscale,
+	}
+
+	for _, config := range configs {
+		if _, err := New(config); err == nil {
+			t.Errorf("New(%+v) err = nil; want error", config)
+		}
+	}
+}
+
+func testConfig(pace string, timeout time.Duration, workers, connections int) Config {
+	config := DefaultConfig()
+	config.Pace = pace
+	config.Timeout = timeout
+	config.Workers = workers
+	config.Connections = connections
+	return config
+}
+
+func runReplay(t *testing.T, config Config, input string) (*Summary, error) {
+	t.Helper()
+
+	replayer, err := New(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return replayer.Run(context.Background(), NewJSONLSource(strings.NewReader(input)), DiscardResults)
 }
 
 // Helper function to create test request data
@@ -20,9 +20,12 @@ package ripley
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -31,22 +34,100 @@ var (
 )
 
 type Request struct {
-	Method    string            `json:"method"`
-	Url       string            `json:"url"`
-	Body      string            `json:"body"`
-	Timestamp time.Time         `json:"timestamp"`
-	Headers   map[string]string `json:"headers"`
+	Method string `json:"method"`
+	Url    string `json:"url"`
+	Body   string `json:"body"`
+	// Base64 encoded body for binary payloads, mutually exclusive with Body
+	BodyBase64 string    `json:"bodyBase64,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	Headers    Headers   `json:"headers"`
+	// Status code of the original response, when recorded by the source
+	ExpectedStatus int `json:"expectedStatus,omitempty"`
+	// Latency of the original response in nanoseconds, when recorded by the source
+	OriginalLatency time.Duration `json:"originalLatency,omitempty"`
+	// Checked against the response instead of the global assertions
+	Assertions *Assertions `json:"assertions,omitempty"`
+
+	// Pacer phase the request was sent in
+	phase int
+	rate  float64
+	// When the pacer intended the request to be sent
+	scheduled time.Time
+}
+
+// Headers maps header names to their values. In JSON, each header is either
+// a single string or an array of strings for repeated headers, e.g.
+// {"Accept": "text/html", "Cookie": ["a=1", "b=2"]}
+type Headers map[string][]string
+
+func (h *Headers) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	if raw == nil {
+		*h = nil
+		return nil
+	}
+
+	headers := make(Headers, len(raw))
+
+	for name, value := range raw {
+		var single string
+		if err := json.Unmarshal(value, &single); err == nil {
+			headers[name] = []string{single}
+			continue
+		}
+
+		var multiple []string
+		if err := json.Unmarshal(value, &multiple); err != nil {
+			return fmt.Errorf("header %s must be a string or an array of strings", name)
+		}
+		headers[name] = multiple
+	}
+
+	*h = headers
+	return nil
+}
+
+// MarshalJSON writes single valued headers as plain strings, so requests
+// without repeated headers keep their original format
+func (h Headers) MarshalJSON() ([]byte, error) {
+	if h == nil {
+		return []byte("null"), nil
+	}
+
+	raw := make(map[string]any, len(h))
+
+	for name, values := range h {
+		if len(values) == 1 {
+			raw[name] = values[0]
+		} else {
+			raw[name] = values
+		}
+	}
+
+	return json.Marshal(raw)
 }
 
 func (r *Request) httpRequest() (*http.Request, error) {
-	req, err := http.NewRequest(r.Method, r.Url, bytes.NewReader([]byte(r.Body)))
+	body, err := r.body()
 
 	if err != nil {
 		return nil, err
 	}
 
-	for k, v := range r.Headers {
-		req.Header.Add(k, v)
+	req, err := http.NewRequest(r.Method, r.Url, bytes.NewReader(body))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for k, values := range r.Headers {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
 	}
 
 	if host := req.Header.Get("Host"); host != "" {
@@ -56,6 +137,20 @@ func (r *Request) httpRequest() (*http.Request, error) {
 	return req, nil
 }
 
+func (r *Request) body() ([]byte, error) {
+	if r.BodyBase64 == "" {
+		return []byte(r.Body), nil
+	}
+
+	body, err := base64.StdEncoding.DecodeString(r.BodyBase64)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid bodyBase64: %w", err)
+	}
+
+	return body, nil
+}
+
 func unmarshalRequest(jsonRequest []byte) (*Request, error) {
 	req := &Request{}
 	err := json.Unmarshal(jsonRequest, &req)
@@ -77,9 +172,58 @@ func unmarshalRequest(jsonRequest []byte) (*Request, error) {
 		return req, fmt.Errorf("missing required key: timestamp")
 	}
 
+	if req.Body != "" && req.BodyBase64 != "" {
+		return req, errors.New("body and bodyBase64 are mutually exclusive")
+	}
+
+	if _, err := req.body(); err != nil {
+		return req, err
+	}
+
+	if req.ExpectedStatus != 0 && (req.ExpectedStatus < 100 || req.ExpectedStatus > 599) {
+		return req, fmt.Errorf("invalid expectedStatus: %d", req.ExpectedStatus)
+	}
+
+	if req.OriginalLatency < 0 {
+		return req, fmt.Errorf("invalid originalLatency: %d", req.OriginalLatency)
+	}
+
+	if req.Assertions != nil {
+		if err := req.Assertions.compile(); err != nil {
+			return req, fmt.Errorf("invalid assertions: %w", err)
+		}
+	}
+
+	for name, values := range req.Headers {
+		if !validHeaderName(name) {
+			return req, fmt.Errorf("invalid header name: %q", name)
+		}
+
+		for _, value := range values {
+			if strings.ContainsAny(value, "\r\n\x00") {
+				return req, fmt.Errorf("invalid value for header %s: %q", name, value)
+			}
+		}
+	}
+
 	return req, nil
 }
 
+// validHeaderName reports whether name is a valid HTTP header field name token
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range []byte(name) {
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
 func validMethod(requestMethod string) bool {
 	for _, method := range validMethods {
 		if requestMethod == method {
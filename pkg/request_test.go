@@ -19,6 +19,9 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package ripley
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"testing"
 	"time"
 )
@@ -58,3 +61,118 @@ func TestUnrmarshalValid(t *testing.T) {
 		t.Errorf("req.Timestamp = %v; want %v", req.Timestamp, expectedTime)
 	}
 }
+
+func TestUnmarshalHeaders(t *testing.T) {
+	jsonRequest := `{"method": "GET", "url": "http://example.com", "timestamp": "2021-11-08T18:59:59.9Z", "headers": {"Accept": "text/html", "Cookie": ["a=1", "b=2"], "Host": "example.org"}}`
+	req, err := unmarshalRequest([]byte(jsonRequest))
+
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+
+	httpReq, err := req.httpRequest()
+
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+
+	if got := httpReq.Header.Values("Cookie"); len(got) != 2 || got[0] != "a=1" || got[1] != "b=2" {
+		t.Errorf(`httpReq.Header.Values("Cookie") = %v; want [a=1 b=2]`, got)
+	}
+
+	if got := httpReq.Header.Get("Accept"); got != "text/html" {
+		t.Errorf(`httpReq.Header.Get("Accept") = %v; want text/html`, got)
+	}
+
+	if httpReq.Host != "example.org" {
+		t.Errorf("httpReq.Host = %v; want example.org", httpReq.Host)
+	}
+}
+
+func TestMarshalHeaders(t *testing.T) {
+	headers := Headers{"Cookie": {"a=1", "b=2"}}
+	headers["Accept"] = []string{"text/html"}
+
+	data, err := json.Marshal(headers)
+
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+
+	want := `{"Accept":"text/html","Cookie":["a=1","b=2"]}`
+	if string(data) != want {
+		t.Errorf("json.Marshal(headers) = %s; want %s", data, want)
+	}
+}
+
+func TestUnmarshalBodyBase64(t *testing.T) {
+	jsonRequest := `{"method": "POST", "url": "http://example.com", "timestamp": "2021-11-08T18:59:59.9Z", "bodyBase64": "AAH/"}`
+	req, err := unmarshalRequest([]byte(jsonRequest))
+
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+
+	httpReq, err := req.httpRequest()
+
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+
+	body, err := io.ReadAll(httpReq.Body)
+
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+
+	if !bytes.Equal(body, []byte{0x00, 0x01, 0xff}) {
+		t.Errorf("body = %v; want [0 1 255]", body)
+	}
+}
+
+func TestUnmarshalInvalidBodyAndHeaders(t *testing.T) {
+	prefix := `{"method": "POST", "url": "http://example.com", "timestamp": "2021-11-08T18:59:59.9Z", `
+
+	tests := map[string]string{
+		`"body": "a", "bodyBase64": "YQ=="}`:   "body and bodyBase64 are mutually exclusive",
+		`"bodyBase64": "not base64!"}`:         "invalid bodyBase64: illegal base64 data at input byte 3",
+		`"headers": {"Bad Name": "a"}}`:        `invalid header name: "Bad Name"`,
+		`"headers": {"X-A": ["a", "b\r\nc"]}}`: "invalid value for header X-A: \"b\\r\\nc\"",
+		`"headers": {"X-A": 1}}`:               "header X-A must be a string or an array of strings",
+		`"assertions": {"bodyMatches": "("}}`:  "invalid assertions: invalid bodyMatches: error parsing regexp: missing closing ): `(`",
+	}
+
+	for suffix, want := range tests {
+		_, err := unmarshalRequest([]byte(prefix + suffix))
+
+		if err == nil || err.Error() != want {
+			t.Errorf("unmarshalRequest(%s) err = %v; want %s", suffix, err, want)
+		}
+	}
+}
+
+func TestUnmarshalOriginalResponse(t *testing.T) {
+	prefix := `{"method": "GET", "url": "http://example.com", "timestamp": "2021-11-08T18:59:59.9Z", `
+
+	req, err := unmarshalRequest([]byte(prefix + `"expectedStatus": 404, "originalLatency": 2500000}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if req.ExpectedStatus != 404 || req.OriginalLatency != 2500*time.Microsecond {
+		t.Errorf("req = %d %v; want 404 2.5ms", req.ExpectedStatus, req.OriginalLatency)
+	}
+
+	tests := map[string]string{
+		`"expectedStatus": 42}`:  "invalid expectedStatus: 42",
+		`"originalLatency": -1}`: "invalid originalLatency: -1",
+	}
+
+	for suffix, want := range tests {
+		_, err := unmarshalRequest([]byte(prefix + suffix))
+
+		if err == nil || err.Error() != want {
+			t.Errorf("unmarshalRequest(%s) err = %v; want %s", suffix, err, want)
+		}
+	}
+}
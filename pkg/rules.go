@@ -0,0 +1,297 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// Rules transform requests after they are read and before they are replayed.
+// Every rule matching a request is applied to it, in order.
+type Rules struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule rewrites the requests it matches
+type Rule struct {
+	// A rule without conditions matches every request
+	Match RuleMatch `json:"match,omitempty" yaml:"match,omitempty"`
+	// Regular expression rewrites of the URL host and path
+	Host *Rewrite `json:"host,omitempty" yaml:"host,omitempty"`
+	Path *Rewrite `json:"path,omitempty" yaml:"path,omitempty"`
+	// Replaces the method
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	// Headers replaced or added, and removed, regardless of case
+	SetHeaders    map[string]string `json:"setHeaders,omitempty" yaml:"setHeaders,omitempty"`
+	RemoveHeaders []string          `json:"removeHeaders,omitempty" yaml:"removeHeaders,omitempty"`
+	// Query parameters replaced or added, and removed
+	SetQuery    map[string]string `json:"setQuery,omitempty" yaml:"setQuery,omitempty"`
+	RemoveQuery []string          `json:"removeQuery,omitempty" yaml:"removeQuery,omitempty"`
+}
+
+// RuleMatch holds the conditions a request must meet for a rule to apply
+type RuleMatch struct {
+	// The method must be one of these
+	Methods []string `json:"methods,omitempty" yaml:"methods,omitempty"`
+	// Regular expressions the URL host and path must match
+	Host string `json:"host,omitempty" yaml:"host,omitempty"`
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// Rewrite replaces the matches of a regular expression, the replacement can
+// refer to groups of the pattern as $1 or ${name}
+type Rewrite struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Replace string `json:"replace" yaml:"replace"`
+}
+
+// LoadRules reads rules from a YAML file, or a JSON file when its name ends in .json
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &Rules{}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(rules)
+	} else {
+		err = yaml.UnmarshalStrict(data, rules)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules in %s: %w", path, err)
+	}
+
+	if _, err := newTransformer(rules); err != nil {
+		return nil, fmt.Errorf("invalid rules in %s: %w", path, err)
+	}
+
+	return rules, nil
+}
+
+// transformer applies compiled rules to requests. It is only used by the
+// goroutine reading the source.
+type transformer struct {
+	rules []*compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	methods   []string
+	matchHost *regexp.Regexp
+	matchPath *regexp.Regexp
+	host      *regexp.Regexp
+	path      *regexp.Regexp
+}
+
+// newTransformer returns nil when there are no rules
+func newTransformer(rules *Rules) (*transformer, error) {
+	if rules == nil || len(rules.Rules) == 0 {
+		return nil, nil
+	}
+
+	t := &transformer{}
+
+	for i, rule := range rules.Rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		t.rules = append(t.rules, compiled)
+	}
+
+	return t, nil
+}
+
+func compileRule(rule Rule) (*compiledRule, error) {
+	compiled := &compiledRule{Rule: rule}
+
+	for _, method := range rule.Match.Methods {
+		if !validMethod(strings.ToUpper(method)) {
+			return nil, fmt.Errorf("invalid method: %s", method)
+		}
+		compiled.methods = append(compiled.methods, strings.ToUpper(method))
+	}
+
+	if rule.Method != "" && !validMethod(rule.Method) {
+		return nil, fmt.Errorf("invalid method: %s", rule.Method)
+	}
+
+	var err error
+
+	if compiled.matchHost, err = compileOptional(rule.Match.Host); err != nil {
+		return nil, fmt.Errorf("invalid match host: %w", err)
+	}
+
+	if compiled.matchPath, err = compileOptional(rule.Match.Path); err != nil {
+		return nil, fmt.Errorf("invalid match path: %w", err)
+	}
+
+	if rule.Host != nil {
+		if compiled.host, err = regexp.Compile(rule.Host.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid host pattern: %w", err)
+		}
+	}
+
+	if rule.Path != nil {
+		if compiled.path, err = regexp.Compile(rule.Path.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid path pattern: %w", err)
+		}
+	}
+
+	for name, value := range rule.SetHeaders {
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("invalid header name: %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return nil, fmt.Errorf("invalid value for header %s: %q", name, value)
+		}
+	}
+
+	return compiled, nil
+}
+
+func compileOptional(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// apply rewrites req in place with every matching rule
+func (t *transformer) apply(req *Request) error {
+	parsedURL, err := url.Parse(req.Url)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+
+	changed := false
+
+	for _, rule := range t.rules {
+		if !rule.matches(req, parsedURL) {
+			continue
+		}
+
+		rule.rewrite(req, parsedURL)
+		changed = true
+	}
+
+	if changed {
+		req.Url = parsedURL.String()
+	}
+
+	return nil
+}
+
+func (r *compiledRule) matches(req *Request, parsedURL *url.URL) bool {
+	if len(r.methods) > 0 && !slices.Contains(r.methods, req.Method) {
+		return false
+	}
+
+	if r.matchHost != nil && !r.matchHost.MatchString(parsedURL.Host) {
+		return false
+	}
+
+	if r.matchPath != nil && !r.matchPath.MatchString(parsedURL.Path) {
+		return false
+	}
+
+	return true
+}
+
+func (r *compiledRule) rewrite(req *Request, parsedURL *url.URL) {
+	if r.host != nil {
+		parsedURL.Host = r.host.ReplaceAllString(parsedURL.Host, r.Host.Replace)
+	}
+
+	if r.path != nil {
+		parsedURL.Path = r.path.ReplaceAllString(parsedURL.Path, r.Path.Replace)
+		parsedURL.RawPath = ""
+	}
+
+	if r.Method != "" {
+		req.Method = r.Method
+	}
+
+	for _, name := range r.RemoveHeaders {
+		removeHeader(req.Headers, name)
+	}
+
+	for _, name := range sortedKeys(r.SetHeaders) {
+		if req.Headers == nil {
+			req.Headers = make(Headers)
+		}
+		removeHeader(req.Headers, name)
+		req.Headers[name] = []string{r.SetHeaders[name]}
+	}
+
+	if len(r.SetQuery) > 0 || len(r.RemoveQuery) > 0 {
+		query := parsedURL.Query()
+		for _, name := range r.RemoveQuery {
+			query.Del(name)
+		}
+		for name, value := range r.SetQuery {
+			query.Set(name, value)
+		}
+		parsedURL.RawQuery = query.Encode()
+	}
+}
+
+// removeHeader deletes every spelling of a header name
+func removeHeader(headers Headers, name string) {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			delete(headers, key)
+		}
+	}
+}
+
+// transformSource applies rules to the requests of a source. Requests the
+// rules cannot be applied to are reported as invalid.
+type transformSource struct {
+	source      RequestSource
+	transformer *transformer
+}
+
+func (s *transformSource) Next() (*Request, error) {
+	req, err := s.source.Next()
+	if err != nil {
+		return req, err
+	}
+
+	if err := s.transformer.apply(req); err != nil {
+		return nil, &InvalidRequestError{Request: req, Err: err}
+	}
+
+	return req, nil
+}
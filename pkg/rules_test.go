@@ -0,0 +1,154 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testRules = `
+rules:
+  - host:
+      pattern: ^api\.example\.com$
+      replace: localhost:8080
+    removeHeaders: [authorization, Cookie]
+    setHeaders:
+      X-Load-Test: "true"
+  - match:
+      methods: [get]
+      path: ^/v1/
+    path:
+      pattern: ^/v1/(.*)
+      replace: /v2/$1
+    setQuery:
+      cache: bust
+    removeQuery: [session]
+`
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "rules.yaml")
+	jsonPath := filepath.Join(dir, "rules.json")
+
+	if err := os.WriteFile(yamlPath, []byte(testRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jsonPath, []byte(`{"rules": [{"method": "POST", "removeHeaders": ["Cookie"]}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := LoadRules(yamlPath)
+	if err != nil {
+		t.Fatalf("LoadRules(yaml) err = %v; want nil", err)
+	}
+	if len(rules.Rules) != 2 || rules.Rules[1].Path.Replace != "/v2/$1" {
+		t.Errorf("LoadRules(yaml) = %+v; want 2 rules", rules)
+	}
+
+	rules, err = LoadRules(jsonPath)
+	if err != nil {
+		t.Fatalf("LoadRules(json) err = %v; want nil", err)
+	}
+	if len(rules.Rules) != 1 || rules.Rules[0].Method != "POST" {
+		t.Errorf("LoadRules(json) = %+v; want 1 rule", rules)
+	}
+}
+
+func TestLoadRulesInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown.yaml":   "rules:\n  - rename: x\n",
+		"pattern.yaml":   "rules:\n  - path: {pattern: '(', replace: x}\n",
+		"method.yaml":    "rules:\n  - match: {methods: [FETCH]}\n",
+		"header.yaml":    "rules:\n  - setHeaders: {'Bad Header': x}\n",
+		"unknown.json":   `{"rules": [{"rename": "x"}]}`,
+		"malformed.json": `{"rules": [`,
+	}
+
+	dir := t.TempDir()
+
+	for name, content := range tests {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadRules(path); err == nil {
+			t.Errorf("LoadRules(%s) err = nil; want error", name)
+		}
+	}
+}
+
+func TestTransformSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yml")
+	if err := os.WriteFile(path, []byte(testRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	transformer, err := newTransformer(rules)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	input := strings.Join([]string{
+		`{"method": "GET", "url": "http://api.example.com/v1/users?id=1&session=abc", "timestamp": "2021-11-08T18:59:50.9Z", "headers": {"Authorization": "Bearer expired", "Accept": "application/json"}}`,
+		`{"method": "POST", "url": "http://api.example.com/v1/users", "timestamp": "2021-11-08T18:59:51.9Z"}`,
+		`{"method": "GET", "url": "http://other.example.com/health", "timestamp": "2021-11-08T18:59:52.9Z"}`,
+		`{"method": "GET", "url": "http://api.example.com/%zz", "timestamp": "2021-11-08T18:59:53.9Z"}`,
+	}, "\n")
+
+	source := &transformSource{source: NewJSONLSource(strings.NewReader(input)), transformer: transformer}
+
+	tests := []struct {
+		url     string
+		headers Headers
+	}{
+		{"http://localhost:8080/v2/users?cache=bust&id=1", Headers{"Accept": {"application/json"}, "X-Load-Test": {"true"}}},
+		{"http://localhost:8080/v1/users", Headers{"X-Load-Test": {"true"}}},
+		{"http://other.example.com/health", Headers{"X-Load-Test": {"true"}}},
+	}
+
+	for _, test := range tests {
+		req, err := source.Next()
+		if err != nil {
+			t.Fatalf("Next() err = %v; want nil", err)
+		}
+
+		if req.Url != test.url {
+			t.Errorf("req.Url = %s; want %s", req.Url, test.url)
+		}
+
+		if !reflect.DeepEqual(req.Headers, test.headers) {
+			t.Errorf("req.Headers = %v; want %v", req.Headers, test.headers)
+		}
+	}
+
+	if _, err := source.Next(); !errors.As(err, new(*InvalidRequestError)) {
+		t.Errorf("Next() err = %v; want InvalidRequestError for an invalid URL", err)
+	}
+}
@@ -0,0 +1,365 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Mismatching requests kept as examples in the summary
+	maxShadowExamples = 10
+	// Bodies in differences are truncated to keep results small
+	maxDiffBodyLength = 200
+)
+
+// ShadowConfig sends every request to both a baseline and a candidate target
+// and compares their responses. Shadowing is off when both targets are empty.
+type ShadowConfig struct {
+	// Replace the host, and the scheme when given, of request URLs,
+	// e.g. "http://old-service:8080" or "new-service:8080"
+	Baseline  string
+	Candidate string
+	// Response headers compared besides the status code and the body
+	Headers []string
+	// Keys removed at any depth from JSON bodies before comparing them,
+	// e.g. timestamps and request IDs
+	IgnoreFields []string
+}
+
+func (c ShadowConfig) enabled() bool {
+	return c.Baseline != "" || c.Candidate != ""
+}
+
+// BaselineResult is the outcome of a request sent to the shadow baseline
+type BaselineResult struct {
+	StatusCode int           `json:"statusCode"`
+	Latency    time.Duration `json:"latency"`
+	ErrorMsg   string        `json:"error"`
+}
+
+// Difference between the baseline and candidate responses to a request
+type Difference struct {
+	// "status", "header", "body" or "error"
+	Kind string `json:"kind"`
+	// Header name for header differences
+	Name      string `json:"name,omitempty"`
+	Baseline  string `json:"baseline"`
+	Candidate string `json:"candidate"`
+}
+
+func (d Difference) key() string {
+	if d.Name == "" {
+		return d.Kind
+	}
+	return d.Kind + " " + d.Name
+}
+
+// ShadowSummary aggregates the differences found in shadow mode
+type ShadowSummary struct {
+	Compared int     `json:"compared"`
+	Diffs    int     `json:"diffs"`
+	DiffRate float64 `json:"diffRate"`
+	// Responses with a difference of each kind, e.g. "status" or "header Content-Type"
+	DiffsByKind map[string]int `json:"diffsByKind"`
+	// The first requests whose responses differed
+	Examples []*ShadowExample `json:"examples"`
+}
+
+// ShadowExample is a request whose baseline and candidate responses differed
+type ShadowExample struct {
+	Method      string       `json:"method"`
+	Url         string       `json:"url"`
+	Differences []Difference `json:"differences"`
+}
+
+type shadowTarget struct {
+	scheme string
+	host   string
+}
+
+func parseShadowTarget(target string) (shadowTarget, error) {
+	rawURL := target
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "//" + rawURL
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.Host == "" || strings.Trim(parsedURL.Path, "/") != "" {
+		return shadowTarget{}, fmt.Errorf("invalid shadow target: %s", target)
+	}
+
+	return shadowTarget{scheme: parsedURL.Scheme, host: parsedURL.Host}, nil
+}
+
+// rewrite returns a copy of req sent to the target
+func (t shadowTarget) rewrite(req *Request) *Request {
+	parsedURL, err := url.Parse(req.Url)
+	if err != nil {
+		// Sending the request fails the same way for both targets
+		return req
+	}
+
+	parsedURL.Host = t.host
+	if t.scheme != "" {
+		parsedURL.Scheme = t.scheme
+	}
+
+	rewritten := *req
+	rewritten.Url = parsedURL.String()
+	return &rewritten
+}
+
+// shadowDiffer sends requests to both shadow targets and compares the responses.
+// It is shared by all workers.
+type shadowDiffer struct {
+	baseline     shadowTarget
+	candidate    shadowTarget
+	headers      []string
+	ignoreFields map[string]bool
+}
+
+// newShadowDiffer returns nil when shadowing is off
+func newShadowDiffer(config ShadowConfig) (*shadowDiffer, error) {
+	if !config.enabled() {
+		return nil, nil
+	}
+
+	if config.Baseline == "" || config.Candidate == "" {
+		return nil, fmt.Errorf("shadow mode needs both a baseline and a candidate")
+	}
+
+	baseline, err := parseShadowTarget(config.Baseline)
+	if err != nil {
+		return nil, err
+	}
+
+	candidate, err := parseShadowTarget(config.Candidate)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range config.Headers {
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("invalid shadow header name: %q", name)
+		}
+	}
+
+	ignoreFields := make(map[string]bool, len(config.IgnoreFields))
+	for _, field := range config.IgnoreFields {
+		ignoreFields[field] = true
+	}
+
+	return &shadowDiffer{
+		baseline:     baseline,
+		candidate:    candidate,
+		headers:      config.Headers,
+		ignoreFields: ignoreFields,
+	}, nil
+}
+
+// execute sends req to both targets at the same time. The result is the
+// candidate's, with the baseline's outcome and the differences attached.
+func (s *shadowDiffer) execute(ctx context.Context, client *http.Client, req *Request, checker *responseChecker) *Result {
+	baselineReq := s.baseline.rewrite(req)
+	candidateReq := s.candidate.rewrite(req)
+
+	var (
+		baseline     *Result
+		baselineResp *http.Response
+		baselineBody []byte
+	)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		baseline, baselineResp, baselineBody = fetch(ctx, client, baselineReq, time.Now())
+	}()
+
+	result, resp, body := fetch(ctx, client, candidateReq, time.Now())
+	<-done
+
+	if resp != nil {
+		checker.check(candidateReq, resp, body, result)
+	}
+
+	result.Baseline = &BaselineResult{StatusCode: baseline.StatusCode, Latency: baseline.Latency, ErrorMsg: baseline.ErrorMsg}
+	result.Differences = s.diff(baseline, baselineResp, baselineBody, result, resp, body)
+
+	return result
+}
+
+func (s *shadowDiffer) diff(baseline *Result, baselineResp *http.Response, baselineBody []byte, candidate *Result, candidateResp *http.Response, candidateBody []byte) []Difference {
+	// Requests failing on both targets do not tell them apart
+	if baselineResp == nil && candidateResp == nil {
+		return nil
+	}
+
+	if baselineResp == nil || candidateResp == nil {
+		return []Difference{{Kind: "error", Baseline: baseline.ErrorMsg, Candidate: candidate.ErrorMsg}}
+	}
+
+	var differences []Difference
+
+	if baselineResp.StatusCode != candidateResp.StatusCode {
+		differences = append(differences, Difference{
+			Kind:      "status",
+			Baseline:  strconv.Itoa(baselineResp.StatusCode),
+			Candidate: strconv.Itoa(candidateResp.StatusCode),
+		})
+	}
+
+	for _, name := range s.headers {
+		baselineValues := baselineResp.Header.Values(name)
+		candidateValues := candidateResp.Header.Values(name)
+
+		if !slices.Equal(baselineValues, candidateValues) {
+			differences = append(differences, Difference{
+				Kind:      "header",
+				Name:      http.CanonicalHeaderKey(name),
+				Baseline:  strings.Join(baselineValues, ", "),
+				Candidate: strings.Join(candidateValues, ", "),
+			})
+		}
+	}
+
+	if !bytes.Equal(s.normaliseBody(baselineBody), s.normaliseBody(candidateBody)) {
+		differences = append(differences, Difference{
+			Kind:      "body",
+			Baseline:  truncate(baselineBody, maxDiffBodyLength),
+			Candidate: truncate(candidateBody, maxDiffBodyLength),
+		})
+	}
+
+	return differences
+}
+
+// normaliseBody re-encodes JSON bodies without the ignored fields, so key order
+// and formatting do not count as differences. Numbers are kept as written, so
+// large integers such as IDs are compared exactly. Other bodies are only trimmed.
+func (s *shadowDiffer) normaliseBody(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document any
+	if err := decoder.Decode(&document); err != nil || decoder.More() {
+		return bytes.TrimSpace(body)
+	}
+
+	normalised, err := json.Marshal(s.removeIgnoredFields(document))
+	if err != nil {
+		return bytes.TrimSpace(body)
+	}
+
+	return normalised
+}
+
+func (s *shadowDiffer) removeIgnoredFields(document any) any {
+	switch v := document.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if s.ignoreFields[key] {
+				delete(v, key)
+				continue
+			}
+			v[key] = s.removeIgnoredFields(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = s.removeIgnoredFields(value)
+		}
+	}
+	return document
+}
+
+func truncate(body []byte, length int) string {
+	if len(body) <= length {
+		return string(body)
+	}
+	return string(body[:length]) + "..."
+}
+
+// shadowCollector aggregates the differences of shadowed results.
+// It is not safe for concurrent use.
+type shadowCollector struct {
+	compared    int
+	diffs       int
+	diffsByKind map[string]int
+	examples    []*ShadowExample
+}
+
+func newShadowCollector() *shadowCollector {
+	return &shadowCollector{diffsByKind: make(map[string]int)}
+}
+
+func (c *shadowCollector) record(result *Result) {
+	if result.Baseline == nil {
+		return
+	}
+
+	c.compared++
+
+	if len(result.Differences) == 0 {
+		return
+	}
+
+	c.diffs++
+
+	for _, difference := range result.Differences {
+		c.diffsByKind[difference.key()]++
+	}
+
+	if len(c.examples) < maxShadowExamples {
+		c.examples = append(c.examples, &ShadowExample{
+			Method:      result.Request.Method,
+			Url:         result.Request.Url,
+			Differences: result.Differences,
+		})
+	}
+}
+
+// summary returns nil when no result was shadowed
+func (c *shadowCollector) summary() *ShadowSummary {
+	if c.compared == 0 {
+		return nil
+	}
+
+	summary := &ShadowSummary{
+		Compared:    c.compared,
+		Diffs:       c.diffs,
+		DiffRate:    float64(c.diffs) / float64(c.compared),
+		DiffsByKind: make(map[string]int, len(c.diffsByKind)),
+		Examples:    c.examples,
+	}
+
+	for kind, count := range c.diffsByKind {
+		summary.DiffsByKind[kind] = count
+	}
+
+	return summary
+}
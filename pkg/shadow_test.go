@@ -0,0 +1,169 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShadowTargetRewrite(t *testing.T) {
+	tests := map[string]string{
+		"localhost:8080":          "http://localhost:8080/users?id=1",
+		"https://new-service":     "https://new-service/users?id=1",
+		"http://new-service:8080": "http://new-service:8080/users?id=1",
+	}
+
+	req := &Request{Method: "GET", Url: "http://example.com/users?id=1"}
+
+	for target, want := range tests {
+		shadowTarget, err := parseShadowTarget(target)
+		if err != nil {
+			t.Fatalf("parseShadowTarget(%s) err = %v; want nil", target, err)
+		}
+
+		if got := shadowTarget.rewrite(req).Url; got != want {
+			t.Errorf("rewrite(%s) = %s; want %s", target, got, want)
+		}
+	}
+
+	if req.Url != "http://example.com/users?id=1" {
+		t.Errorf("req.Url = %s; want the original URL", req.Url)
+	}
+}
+
+func TestNewShadowDifferInvalid(t *testing.T) {
+	configs := []ShadowConfig{
+		{Baseline: "localhost:8080"},
+		{Candidate: "localhost:8080"},
+		{Baseline: "localhost:8080", Candidate: "http://"},
+		{Baseline: "localhost:8080", Candidate: "localhost:8081/api"},
+		{Baseline: "localhost:8080", Candidate: "localhost:8081", Headers: []string{"Bad Header"}},
+	}
+
+	for _, config := range configs {
+		if _, err := newShadowDiffer(config); err == nil {
+			t.Errorf("newShadowDiffer(%+v) err = nil; want error", config)
+		}
+	}
+
+	if differ, err := newShadowDiffer(ShadowConfig{}); differ != nil || err != nil {
+		t.Errorf("newShadowDiffer({}) = %v, %v; want nil, nil", differ, err)
+	}
+}
+
+func TestShadowDiff(t *testing.T) {
+	differ, err := newShadowDiffer(ShadowConfig{
+		Baseline:     "localhost:8080",
+		Candidate:    "localhost:8081",
+		Headers:      []string{"content-type"},
+		IgnoreFields: []string{"requestId"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	response := func(status int, contentType string) *http.Response {
+		return &http.Response{StatusCode: status, Header: http.Header{"Content-Type": {contentType}}}
+	}
+
+	baseline := &Result{StatusCode: 200}
+	candidate := &Result{StatusCode: 200}
+
+	same := differ.diff(baseline, response(200, "application/json"), []byte(`{"id": 1, "items": [{"requestId": "a"}]}`),
+		candidate, response(200, "application/json"), []byte(`{"items":[{"requestId":"b"}],"id":1}`))
+	if len(same) != 0 {
+		t.Errorf("diff of equivalent responses = %+v; want none", same)
+	}
+
+	// Both IDs round to the same float64
+	ids := differ.diff(baseline, response(200, "application/json"), []byte(`{"id": 9007199254740993}`),
+		candidate, response(200, "application/json"), []byte(`{"id": 9007199254740992}`))
+	if len(ids) != 1 || ids[0].Kind != "body" {
+		t.Errorf("diff of large integer IDs = %+v; want a body difference", ids)
+	}
+
+	trailing := differ.diff(baseline, response(200, "application/json"), []byte(`{"id": 1}`),
+		candidate, response(200, "application/json"), []byte(`{"id": 1} {"id": 2}`))
+	if len(trailing) != 1 || trailing[0].Kind != "body" {
+		t.Errorf("diff of a body with trailing data = %+v; want a body difference", trailing)
+	}
+
+	differences := differ.diff(baseline, response(200, "application/json"), []byte(`{"id": 1}`),
+		candidate, response(404, "text/plain"), []byte("not found"))
+
+	var kinds []string
+	for _, difference := range differences {
+		kinds = append(kinds, difference.key())
+	}
+
+	if got := strings.Join(kinds, ","); got != "status,header Content-Type,body" {
+		t.Errorf("diff kinds = %s; want status,header Content-Type,body", got)
+	}
+
+	failed := differ.diff(baseline, response(200, "text/plain"), nil, &Result{ErrorMsg: "connection refused"}, nil, nil)
+	if len(failed) != 1 || failed[0].Kind != "error" || failed[0].Candidate != "connection refused" {
+		t.Errorf("diff with a failed candidate = %+v; want an error difference", failed)
+	}
+
+	if both := differ.diff(&Result{ErrorMsg: "timeout"}, nil, nil, &Result{ErrorMsg: "timeout"}, nil, nil); both != nil {
+		t.Errorf("diff of two failed requests = %+v; want none", both)
+	}
+}
+
+func TestShadowCollector(t *testing.T) {
+	collector := newSummaryCollector()
+	req := &Request{Method: "GET", Url: "http://example.com/"}
+
+	collector.record(&Result{StatusCode: 200, Request: req})
+	if shadow := collector.summary(time.Second, 1).Shadow; shadow != nil {
+		t.Errorf("summary.Shadow = %+v; want nil without shadowed results", shadow)
+	}
+
+	for i := 0; i < maxShadowExamples+2; i++ {
+		collector.record(&Result{StatusCode: 500, Request: req, Baseline: &BaselineResult{StatusCode: 200},
+			Differences: []Difference{{Kind: "status", Baseline: "200", Candidate: "500"}}})
+	}
+	collector.record(&Result{StatusCode: 200, Request: req, Baseline: &BaselineResult{StatusCode: 200}})
+
+	shadow := collector.summary(time.Second, 1).Shadow
+	if shadow == nil {
+		t.Fatalf("summary.Shadow = nil; want shadow summary")
+	}
+
+	if shadow.Compared != maxShadowExamples+3 || shadow.Diffs != maxShadowExamples+2 {
+		t.Errorf("summary.Shadow = %d diffs of %d; want %d of %d", shadow.Diffs, shadow.Compared, maxShadowExamples+2, maxShadowExamples+3)
+	}
+
+	if len(shadow.Examples) != maxShadowExamples {
+		t.Errorf("len(summary.Shadow.Examples) = %d; want %d", len(shadow.Examples), maxShadowExamples)
+	}
+
+	var buffer bytes.Buffer
+	if err := collector.summary(time.Second, 1).WriteText(&buffer); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(buffer.String(), "Shadow differences:") {
+		t.Errorf("WriteText() = %s; want shadow differences", buffer.String())
+	}
+}
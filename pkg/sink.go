@@ -0,0 +1,53 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// ResultSink receives every result of a run, one at a time. Returning an
+// error stops the run.
+type ResultSink interface {
+	Handle(result *Result) error
+}
+
+// ResultSinkFunc adapts a function to a ResultSink
+type ResultSinkFunc func(result *Result) error
+
+func (f ResultSinkFunc) Handle(result *Result) error {
+	return f(result)
+}
+
+// DiscardResults is a ResultSink that ignores all results
+var DiscardResults ResultSink = ResultSinkFunc(func(*Result) error { return nil })
+
+type jsonlSink struct {
+	encoder *json.Encoder
+}
+
+// NewJSONLSink writes results to w in JSON Lines format
+func NewJSONLSink(w io.Writer) ResultSink {
+	return &jsonlSink{encoder: json.NewEncoder(w)}
+}
+
+func (s *jsonlSink) Handle(result *Result) error {
+	return s.encoder.Encode(result)
+}
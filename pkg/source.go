@@ -0,0 +1,91 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"bufio"
+	"io"
+)
+
+// RequestSource provides the requests to replay in timestamp order
+type RequestSource interface {
+	// Next returns the next request, or io.EOF when there are no more requests.
+	// Requests that cannot be parsed are reported as an *InvalidRequestError,
+	// after which Next can be called again.
+	Next() (*Request, error)
+}
+
+// InvalidRequestError is returned by a RequestSource for input that is not a valid request
+type InvalidRequestError struct {
+	// Request holds whatever could be parsed from the input
+	Request *Request
+	Err     error
+}
+
+func (e *InvalidRequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidRequestError) Unwrap() error {
+	return e.Err
+}
+
+type jsonlSource struct {
+	scanner *bufio.Scanner
+}
+
+// NewJSONLSource reads requests in JSON Lines format from r
+func NewJSONLSource(r io.Reader) RequestSource {
+	return &jsonlSource{scanner: bufio.NewScanner(bufio.NewReaderSize(r, 32*1024*1024))}
+}
+
+func (s *jsonlSource) Next() (*Request, error) {
+	if !s.scanner.Scan() {
+		if err := s.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	req, err := unmarshalRequest(s.scanner.Bytes())
+	if err != nil {
+		return nil, &InvalidRequestError{Request: req, Err: err}
+	}
+
+	return req, nil
+}
+
+type sliceSource struct {
+	requests []*Request
+}
+
+// NewSliceSource replays the given requests, which must be in timestamp order
+func NewSliceSource(requests []*Request) RequestSource {
+	return &sliceSource{requests: requests}
+}
+
+func (s *sliceSource) Next() (*Request, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+	return req, nil
+}
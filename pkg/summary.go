@@ -0,0 +1,521 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// Distinct error messages and hosts are capped to keep memory bounded when
+// every value is unique, e.g. because error messages contain the request URL
+const (
+	maxErrorMessages   = 100
+	maxHosts           = 100
+	otherErrorsMessage = "other"
+	otherHosts         = "other"
+)
+
+// Summary is the aggregated report of a replay run
+type Summary struct {
+	Stats
+	InvalidRequests int `json:"invalidRequests"`
+	// Requests sent late because no worker was idle, and requests dropped in
+	// the open model because too many were in flight
+	DelayedRequests int `json:"delayedRequests"`
+	DroppedRequests int `json:"droppedRequests"`
+	// Requests skipped by the filter
+	FilteredRequests int               `json:"filteredRequests"`
+	ExpectedRPS      float64           `json:"expectedRps"`
+	Phases           []*PhaseStats     `json:"phases"`
+	Hosts            map[string]*Stats `json:"hosts"`
+	// Only set when some requests carried their original response
+	Comparison *Comparison `json:"comparison,omitempty"`
+	// Only set in shadow mode
+	Shadow *ShadowSummary `json:"shadow,omitempty"`
+	// Only set when requests were sent, not in dry runs
+	Timings *TimingsSummary `json:"timings,omitempty"`
+}
+
+// Stats holds the aggregated results of the whole run, a phase or a host.
+// Duration is the time between the first and the last result of the scope,
+// except for the whole run where it is the total run time.
+type Stats struct {
+	TotalRequests int `json:"totalRequests"`
+	Errors        int `json:"errors"`
+	// Responses failing their assertions, these are not errors
+	AssertionFailures int            `json:"assertionFailures"`
+	StatusCodes       map[string]int `json:"statusCodes"`
+	ErrorMessages     map[string]int `json:"errorMessages"`
+	Latency           LatencySummary `json:"latency"`
+	// Latency from the scheduled send time, which includes any delay in sending
+	// requests when workers are saturated
+	CorrectedLatency LatencySummary `json:"correctedLatency"`
+	Duration         time.Duration  `json:"duration"`
+	AchievedRPS      float64        `json:"achievedRps"`
+}
+
+// PhaseStats holds the aggregated results of a single pacer phase
+type PhaseStats struct {
+	Phase int     `json:"phase"`
+	Rate  float64 `json:"rate"`
+	Stats
+}
+
+// LatencySummary holds latency statistics of successful requests in nanoseconds
+type LatencySummary struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	P999 time.Duration `json:"p999"`
+	Max  time.Duration `json:"max"`
+}
+
+// summaryCollector aggregates results globally, per phase and per host.
+// It is not safe for concurrent use.
+type summaryCollector struct {
+	total      *resultStats
+	phases     []*phaseResultStats
+	hosts      map[string]*resultStats
+	comparison *comparisonCollector
+	shadow     *shadowCollector
+	timings    *timingsCollector
+}
+
+type phaseResultStats struct {
+	rate  float64
+	stats *resultStats
+}
+
+func newSummaryCollector() *summaryCollector {
+	return &summaryCollector{
+		total:      newResultStats(),
+		hosts:      make(map[string]*resultStats),
+		comparison: newComparisonCollector(),
+		shadow:     newShadowCollector(),
+		timings:    newTimingsCollector(),
+	}
+}
+
+func (c *summaryCollector) record(result *Result) {
+	now := time.Now()
+	c.total.record(result, now)
+
+	for len(c.phases) <= result.Phase {
+		c.phases = append(c.phases, nil)
+	}
+	if c.phases[result.Phase] == nil {
+		c.phases[result.Phase] = &phaseResultStats{rate: result.Rate, stats: newResultStats()}
+	}
+	c.phases[result.Phase].stats.record(result, now)
+
+	host := extractHost(result.Request.Url)
+	hostStats, ok := c.hosts[host]
+	if !ok {
+		if len(c.hosts) >= maxHosts {
+			host = otherHosts
+			hostStats = c.hosts[host]
+		}
+		if hostStats == nil {
+			hostStats = newResultStats()
+			c.hosts[host] = hostStats
+		}
+	}
+	hostStats.record(result, now)
+
+	c.comparison.record(result)
+	c.shadow.record(result)
+	c.timings.record(result)
+}
+
+func (c *summaryCollector) summary(duration time.Duration, expectedRPS float64) *Summary {
+	summary := &Summary{
+		Stats:       c.total.stats(duration),
+		ExpectedRPS: expectedRPS,
+		Phases:      make([]*PhaseStats, 0, len(c.phases)),
+		Hosts:       make(map[string]*Stats, len(c.hosts)),
+	}
+
+	for i, phase := range c.phases {
+		// Phases can elapse without any request being sent in them
+		if phase == nil {
+			continue
+		}
+		summary.Phases = append(summary.Phases, &PhaseStats{
+			Phase: i,
+			Rate:  phase.rate,
+			Stats: phase.stats.stats(phase.stats.window()),
+		})
+	}
+
+	for host, hostStats := range c.hosts {
+		stats := hostStats.stats(hostStats.window())
+		summary.Hosts[host] = &stats
+	}
+
+	summary.Comparison = c.comparison.comparison()
+	summary.Shadow = c.shadow.summary()
+	summary.Timings = c.timings.summary()
+
+	return summary
+}
+
+// resultStats aggregates the results of a single scope
+type resultStats struct {
+	requests          int
+	errors            int
+	assertionFailures int
+	statusCodes       map[int]int
+	errorMessages     map[string]int
+	latency           *histogram
+	correctedLatency  *histogram
+	first             time.Time
+	last              time.Time
+}
+
+func newResultStats() *resultStats {
+	return &resultStats{
+		statusCodes:      make(map[int]int),
+		errorMessages:    make(map[string]int),
+		latency:          newHistogram(),
+		correctedLatency: newHistogram(),
+	}
+}
+
+func (s *resultStats) record(result *Result, now time.Time) {
+	if s.requests == 0 {
+		s.first = now
+	}
+	s.last = now
+	s.requests++
+
+	if result.ErrorMsg != "" {
+		s.errors++
+		s.recordErrorMessage(result.ErrorMsg)
+		return
+	}
+
+	if result.AssertionFailure != "" {
+		s.assertionFailures++
+	}
+
+	s.statusCodes[result.StatusCode]++
+	s.latency.record(result.Latency)
+	s.correctedLatency.record(correctedLatency(result))
+}
+
+// correctedLatency falls back to the latency for results without one, e.g. from tests
+func correctedLatency(result *Result) time.Duration {
+	if result.CorrectedLatency < result.Latency {
+		return result.Latency
+	}
+	return result.CorrectedLatency
+}
+
+func (s *resultStats) recordErrorMessage(msg string) {
+	s.addErrorMessage(msg, 1)
+}
+
+func (s *resultStats) addErrorMessage(msg string, count int) {
+	if _, ok := s.errorMessages[msg]; !ok && len(s.errorMessages) >= maxErrorMessages {
+		msg = otherErrorsMessage
+	}
+	s.errorMessages[msg] += count
+}
+
+// merge adds all results recorded in o to s
+func (s *resultStats) merge(o *resultStats) {
+	if o.requests == 0 {
+		return
+	}
+
+	if s.requests == 0 || o.first.Before(s.first) {
+		s.first = o.first
+	}
+	if o.last.After(s.last) {
+		s.last = o.last
+	}
+
+	s.requests += o.requests
+	s.errors += o.errors
+	s.assertionFailures += o.assertionFailures
+
+	for code, count := range o.statusCodes {
+		s.statusCodes[code] += count
+	}
+
+	for msg, count := range o.errorMessages {
+		s.addErrorMessage(msg, count)
+	}
+
+	s.latency.merge(o.latency)
+	s.correctedLatency.merge(o.correctedLatency)
+}
+
+// window is the time between the first and the last recorded result
+func (s *resultStats) window() time.Duration {
+	return s.last.Sub(s.first)
+}
+
+func (s *resultStats) stats(duration time.Duration) Stats {
+	stats := Stats{
+		TotalRequests:     s.requests,
+		Errors:            s.errors,
+		AssertionFailures: s.assertionFailures,
+		StatusCodes:       make(map[string]int, len(s.statusCodes)),
+		ErrorMessages:     make(map[string]int, len(s.errorMessages)),
+		Latency:           latencySummary(s.latency),
+		CorrectedLatency:  latencySummary(s.correctedLatency),
+		Duration:          duration,
+	}
+
+	for code, count := range s.statusCodes {
+		stats.StatusCodes[strconv.Itoa(code)] = count
+	}
+
+	for msg, count := range s.errorMessages {
+		stats.ErrorMessages[msg] = count
+	}
+
+	if duration > 0 {
+		stats.AchievedRPS = float64(s.requests) / duration.Seconds()
+	}
+
+	return stats
+}
+
+func latencySummary(h *histogram) LatencySummary {
+	return LatencySummary{
+		Min:  h.minimum(),
+		Mean: h.mean(),
+		P50:  h.percentile(50),
+		P90:  h.percentile(90),
+		P95:  h.percentile(95),
+		P99:  h.percentile(99),
+		P999: h.percentile(99.9),
+		Max:  h.maximum(),
+	}
+}
+
+// WriteJSON writes the summary as a single JSON document
+func (s *Summary) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// WriteText writes the summary in a human readable format
+func (s *Summary) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	p := &errWriter{w: tw}
+
+	p.printf("Requests:\t%d\n", s.TotalRequests)
+	p.printf("Errors:\t%d\n", s.Errors)
+	if s.AssertionFailures > 0 {
+		p.printf("Assertion failures:\t%d\n", s.AssertionFailures)
+	}
+	p.printf("Invalid requests:\t%d\n", s.InvalidRequests)
+	if s.FilteredRequests > 0 {
+		p.printf("Filtered requests:\t%d\n", s.FilteredRequests)
+	}
+	if s.DelayedRequests > 0 {
+		p.printf("Delayed requests:\t%d\n", s.DelayedRequests)
+	}
+	if s.DroppedRequests > 0 {
+		p.printf("Dropped requests:\t%d\n", s.DroppedRequests)
+	}
+	p.printf("Duration:\t%s\n", s.Duration.Round(time.Millisecond))
+	p.printf("Expected RPS:\t%.2f\n", s.ExpectedRPS)
+	p.printf("Achieved RPS:\t%.2f\n", s.AchievedRPS)
+
+	p.printf("\nStatus codes:\n")
+	for _, code := range sortedKeys(s.StatusCodes) {
+		p.printf("  %s\t%d\n", code, s.StatusCodes[code])
+	}
+
+	if len(s.ErrorMessages) > 0 {
+		p.printf("\nErrors:\n")
+		for _, msg := range sortedKeys(s.ErrorMessages) {
+			p.printf("  %d\t%s\n", s.ErrorMessages[msg], msg)
+		}
+	}
+
+	writeLatency(p, "Latency", &s.Latency)
+	writeLatency(p, "Corrected latency (from scheduled send time)", &s.CorrectedLatency)
+
+	if len(s.Phases) > 0 {
+		p.printf("\nPhases:\n")
+		p.printf("  phase\trate\t%s\n", statsHeader)
+		for _, phase := range s.Phases {
+			p.printf("  %d\t%g\t%s\n", phase.Phase, phase.Rate, statsRow(&phase.Stats))
+		}
+	}
+
+	if len(s.Hosts) > 0 {
+		p.printf("\nHosts:\n")
+		p.printf("  host\t%s\n", statsHeader)
+		for _, host := range sortedKeys(s.Hosts) {
+			p.printf("  %s\t%s\n", host, statsRow(s.Hosts[host]))
+		}
+	}
+
+	if s.Timings != nil {
+		writeTimings(p, s.Timings)
+	}
+
+	if s.Comparison != nil {
+		writeComparison(p, s.Comparison)
+	}
+
+	if s.Shadow != nil {
+		writeShadow(p, s.Shadow)
+	}
+
+	if p.err != nil {
+		return p.err
+	}
+	return tw.Flush()
+}
+
+func writeLatency(p *errWriter, title string, l *LatencySummary) {
+	p.printf("\n%s:\n", title)
+	p.printf("  min\t%s\n", l.Min)
+	p.printf("  mean\t%s\n", l.Mean)
+	p.printf("  p50\t%s\n", l.P50)
+	p.printf("  p90\t%s\n", l.P90)
+	p.printf("  p95\t%s\n", l.P95)
+	p.printf("  p99\t%s\n", l.P99)
+	p.printf("  p99.9\t%s\n", l.P999)
+	p.printf("  max\t%s\n", l.Max)
+}
+
+func writeTimings(p *errWriter, t *TimingsSummary) {
+	p.printf("\nTimings:\n")
+	p.printf("  Connections reused:\t%d of %d (%.2f%%)\n", t.ConnsReused, t.Requests, t.ConnReuseRate*100)
+	p.printf("\n  phase\tmean\tp50\tp90\tp99\tmax\n")
+
+	phases := []struct {
+		name    string
+		latency *LatencySummary
+	}{
+		{"dns", &t.DNS},
+		{"connect", &t.Connect},
+		{"tls", &t.TLS},
+		{"ttfb", &t.TTFB},
+		{"transfer", &t.Transfer},
+	}
+
+	for _, phase := range phases {
+		l := phase.latency
+		p.printf("  %s\t%s\t%s\t%s\t%s\t%s\n", phase.name, l.Mean, l.P50, l.P90, l.P99, l.Max)
+	}
+}
+
+func writeComparison(p *errWriter, c *Comparison) {
+	p.printf("\nCompared to original responses:\n")
+	p.printf("  Status mismatches:\t%d of %d (%.2f%%)\n", c.StatusMismatches, c.StatusCompared, c.StatusMismatchRate*100)
+	for _, mismatch := range sortedKeys(c.Mismatches) {
+		p.printf("    %s\t%d\n", mismatch, c.Mismatches[mismatch])
+	}
+
+	p.printf("  Latency ratio:\t%s\n", ratioRow(&c.LatencyRatio))
+
+	p.printf("\n  path\t%s\n", comparisonHeader)
+	for _, path := range sortedKeys(c.Paths) {
+		p.printf("  %s\t%s\n", path, comparisonRow(c.Paths[path]))
+	}
+}
+
+func writeShadow(p *errWriter, s *ShadowSummary) {
+	p.printf("\nShadow differences:\t%d of %d (%.2f%%)\n", s.Diffs, s.Compared, s.DiffRate*100)
+	for _, kind := range sortedKeys(s.DiffsByKind) {
+		p.printf("  %s\t%d\n", kind, s.DiffsByKind[kind])
+	}
+
+	if len(s.Examples) > 0 {
+		p.printf("\nShadow examples:\n")
+		for _, example := range s.Examples {
+			p.printf("  %s %s\n", example.Method, example.Url)
+			for _, difference := range example.Differences {
+				p.printf("    %s\t%q\t%q\n", difference.key(), difference.Baseline, difference.Candidate)
+			}
+		}
+	}
+}
+
+const comparisonHeader = "compared\tmismatches\tmismatch_rate\tratio_p50\tratio_p90\tratio_p99"
+
+func comparisonRow(s *ComparisonStats) string {
+	return fmt.Sprintf("%d\t%d\t%.2f%%\t%.2f\t%.2f\t%.2f",
+		s.StatusCompared, s.StatusMismatches, s.StatusMismatchRate*100, s.LatencyRatio.P50, s.LatencyRatio.P90, s.LatencyRatio.P99)
+}
+
+func ratioRow(r *RatioSummary) string {
+	return fmt.Sprintf("min %.2f mean %.2f p50 %.2f p90 %.2f p99 %.2f max %.2f", r.Min, r.Mean, r.P50, r.P90, r.P99, r.Max)
+}
+
+const statsHeader = "requests\terrors\trps\tp50\tp95\tp99\tmax"
+
+func statsRow(s *Stats) string {
+	return fmt.Sprintf("%d\t%d\t%.2f\t%s\t%s\t%s\t%s",
+		s.TotalRequests, s.Errors, s.AchievedRPS, s.Latency.P50, s.Latency.P95, s.Latency.P99, s.Latency.Max)
+}
+
+// Write writes the summary in the given format, either "text" or "json"
+func (s *Summary) Write(w io.Writer, format string) error {
+	switch format {
+	case "text":
+		return s.WriteText(w)
+	case "json":
+		return s.WriteJSON(w)
+	default:
+		return fmt.Errorf("unknown summary format: %s", format)
+	}
+}
+
+// errWriter remembers the first write error so a sequence of prints can be
+// checked once at the end
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...interface{}) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = fmt.Fprintf(e.w, format, args...)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,233 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogramPercentiles(t *testing.T) {
+	h := newHistogram()
+
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	if h.count() != 1000 {
+		t.Errorf("h.count() = %v; want 1000", h.count())
+	}
+
+	if h.minimum() != time.Millisecond {
+		t.Errorf("h.minimum() = %v; want 1ms", h.minimum())
+	}
+
+	if h.maximum() != time.Second {
+		t.Errorf("h.maximum() = %v; want 1s", h.maximum())
+	}
+
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{50, 500 * time.Millisecond},
+		{95, 950 * time.Millisecond},
+		{99, 990 * time.Millisecond},
+		{100, time.Second},
+	}
+
+	for _, test := range tests {
+		got := h.percentile(test.q)
+		// Buckets guarantee a relative error below 1%
+		if !equalsWithinThreshold(got, test.want, test.want/100) {
+			t.Errorf("h.percentile(%v) = %v; want %v", test.q, got, test.want)
+		}
+	}
+
+	if !equalsWithinThreshold(h.mean(), 500500*time.Microsecond, time.Microsecond) {
+		t.Errorf("h.mean() = %v; want 500.5ms", h.mean())
+	}
+}
+
+func TestHistogramExactSmallValues(t *testing.T) {
+	h := newHistogram()
+
+	for i := 0; i < 2*subBucketCount; i++ {
+		h.record(time.Duration(i))
+	}
+
+	if got := h.percentile(50); got != 127 {
+		t.Errorf("h.percentile(50) = %v; want 127ns", got)
+	}
+}
+
+func TestHistogramEmpty(t *testing.T) {
+	h := newHistogram()
+
+	if h.minimum() != 0 || h.maximum() != 0 || h.mean() != 0 || h.percentile(99) != 0 {
+		t.Errorf("empty histogram should report zero values")
+	}
+}
+
+func TestSummaryCollector(t *testing.T) {
+	collector := newSummaryCollector()
+	req := &Request{Url: "http://example.com/"}
+
+	for i := 0; i < 8; i++ {
+		collector.record(&Result{StatusCode: 200, Latency: 10 * time.Millisecond, Request: req})
+	}
+	collector.record(&Result{StatusCode: 503, Latency: 20 * time.Millisecond, Request: req})
+	collector.record(&Result{ErrorMsg: "connection refused", Request: req})
+
+	summary := collector.summary(2*time.Second, 4)
+
+	if summary.TotalRequests != 10 {
+		t.Errorf("summary.TotalRequests = %v; want 10", summary.TotalRequests)
+	}
+
+	if summary.Errors != 1 {
+		t.Errorf("summary.Errors = %v; want 1", summary.Errors)
+	}
+
+	if summary.StatusCodes["200"] != 8 || summary.StatusCodes["503"] != 1 {
+		t.Errorf("summary.StatusCodes = %v; want map[200:8 503:1]", summary.StatusCodes)
+	}
+
+	if summary.ErrorMessages["connection refused"] != 1 {
+		t.Errorf("summary.ErrorMessages = %v; want map[connection refused:1]", summary.ErrorMessages)
+	}
+
+	if summary.Latency.Max != 20*time.Millisecond {
+		t.Errorf("summary.Latency.Max = %v; want 20ms", summary.Latency.Max)
+	}
+
+	if summary.AchievedRPS != 5 {
+		t.Errorf("summary.AchievedRPS = %v; want 5", summary.AchievedRPS)
+	}
+
+	if summary.ExpectedRPS != 4 {
+		t.Errorf("summary.ExpectedRPS = %v; want 4", summary.ExpectedRPS)
+	}
+}
+
+func TestSummaryCollectorPhasesAndHosts(t *testing.T) {
+	collector := newSummaryCollector()
+	foo := &Request{Url: "http://foo.example.com/a"}
+	bar := &Request{Url: "http://bar.example.com/b"}
+
+	collector.record(&Result{StatusCode: 200, Latency: time.Millisecond, Request: foo, Phase: 0, Rate: 1})
+	collector.record(&Result{StatusCode: 200, Latency: time.Millisecond, Request: bar, Phase: 0, Rate: 1})
+	collector.record(&Result{StatusCode: 500, Latency: 50 * time.Millisecond, Request: foo, Phase: 2, Rate: 10})
+
+	summary := collector.summary(time.Second, 0)
+
+	// Phase 1 elapsed without any requests and is omitted
+	if len(summary.Phases) != 2 {
+		t.Fatalf("len(summary.Phases) = %v; want 2", len(summary.Phases))
+	}
+
+	if summary.Phases[0].Phase != 0 || summary.Phases[0].Rate != 1 || summary.Phases[0].TotalRequests != 2 {
+		t.Errorf("summary.Phases[0] = %+v; want phase 0 at rate 1 with 2 requests", summary.Phases[0])
+	}
+
+	if summary.Phases[1].Phase != 2 || summary.Phases[1].Rate != 10 || summary.Phases[1].StatusCodes["500"] != 1 {
+		t.Errorf("summary.Phases[1] = %+v; want phase 2 at rate 10 with one 500", summary.Phases[1])
+	}
+
+	if summary.Hosts["foo.example.com"].TotalRequests != 2 {
+		t.Errorf("foo.example.com requests = %v; want 2", summary.Hosts["foo.example.com"].TotalRequests)
+	}
+
+	if summary.Hosts["bar.example.com"].Latency.Max != time.Millisecond {
+		t.Errorf("bar.example.com max latency = %v; want 1ms", summary.Hosts["bar.example.com"].Latency.Max)
+	}
+}
+
+func TestSummaryCollectorCapsHosts(t *testing.T) {
+	collector := newSummaryCollector()
+
+	for i := 0; i < maxHosts+5; i++ {
+		req := &Request{Url: fmt.Sprintf("http://host%d.example.com/", i)}
+		collector.record(&Result{StatusCode: 200, Request: req})
+	}
+
+	if len(collector.hosts) != maxHosts+1 {
+		t.Errorf("len(collector.hosts) = %v; want %v", len(collector.hosts), maxHosts+1)
+	}
+
+	if collector.hosts[otherHosts].requests != 5 {
+		t.Errorf("other hosts requests = %v; want 5", collector.hosts[otherHosts].requests)
+	}
+}
+
+func TestResultStatsCapsErrorMessages(t *testing.T) {
+	stats := newResultStats()
+	req := &Request{Url: "http://example.com/"}
+
+	for i := 0; i < maxErrorMessages+10; i++ {
+		stats.record(&Result{ErrorMsg: fmt.Sprintf("error %d", i), Request: req}, time.Now())
+	}
+
+	if len(stats.errorMessages) != maxErrorMessages+1 {
+		t.Errorf("len(stats.errorMessages) = %v; want %v", len(stats.errorMessages), maxErrorMessages+1)
+	}
+
+	if stats.errorMessages[otherErrorsMessage] != 10 {
+		t.Errorf("stats.errorMessages[other] = %v; want 10", stats.errorMessages[otherErrorsMessage])
+	}
+}
+
+func TestSummaryWrite(t *testing.T) {
+	collector := newSummaryCollector()
+	collector.record(&Result{StatusCode: 200, Latency: time.Millisecond, Request: &Request{Url: "http://example.com/"}, Rate: 1})
+	summary := collector.summary(time.Second, 1)
+
+	var text bytes.Buffer
+	if err := summary.Write(&text, "text"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, want := range []string{"Requests:          1\n", "Phases:\n", "Hosts:\n", "example.com"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text summary missing %q:\n%s", want, text.String())
+		}
+	}
+
+	var jsonOutput bytes.Buffer
+	if err := summary.Write(&jsonOutput, "json"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var decoded Summary
+	if err := json.Unmarshal(jsonOutput.Bytes(), &decoded); err != nil {
+		t.Fatalf("Failed to decode JSON summary: %v", err)
+	}
+
+	if decoded.TotalRequests != 1 {
+		t.Errorf("decoded.TotalRequests = %v; want 1", decoded.TotalRequests)
+	}
+
+	if err := summary.Write(&text, "xml"); err == nil {
+		t.Errorf("Expected error for unknown format")
+	}
+}
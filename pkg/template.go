@@ -0,0 +1,270 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"fmt"
+	mathrand "math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// Templates often differ on every input line, so once this many are cached
+// further templates are parsed every time they are rendered
+const maxCachedTemplates = 10_000
+
+// templater renders the URL, header values and body of requests as
+// text/template templates. It is shared by all workers.
+type templater struct {
+	mu   sync.Mutex
+	csvs map[string]*csvFile
+	// Each distinct template is parsed once, up to maxCached templates
+	parsed    sync.Map // templateKey -> *parsedTemplate
+	cached    atomic.Int64
+	maxCached int64
+}
+
+func newTemplater() *templater {
+	return &templater{csvs: make(map[string]*csvFile), maxCached: maxCachedTemplates}
+}
+
+type templateKey struct {
+	name string
+	text string
+}
+
+type parsedTemplate struct {
+	tmpl *template.Template
+	err  error
+}
+
+// templateFuncs are bound when templates are parsed. The csv function is
+// replaced when rendering, so it picks rows for the request being rendered.
+var templateFuncs = template.FuncMap{
+	"uuid":    newUUID,
+	"randInt": randInt,
+	"now":     now,
+	"csv": func(path, column string) (string, error) {
+		return "", fmt.Errorf("csv used outside of a request")
+	},
+}
+
+// parse returns the template for text, parsing it on first use
+func (t *templater) parse(name, text string) (*template.Template, error) {
+	key := templateKey{name: name, text: text}
+
+	if cached, ok := t.parsed.Load(key); ok {
+		parsed := cached.(*parsedTemplate)
+		return parsed.tmpl, parsed.err
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		err = fmt.Errorf("invalid template: %w", err)
+	}
+
+	if t.cached.Load() >= t.maxCached {
+		return tmpl, err
+	}
+
+	cached, loaded := t.parsed.LoadOrStore(key, &parsedTemplate{tmpl: tmpl, err: err})
+	if !loaded {
+		t.cached.Add(1)
+	}
+
+	parsed := cached.(*parsedTemplate)
+	return parsed.tmpl, parsed.err
+}
+
+// csvFile is a CSV file with a header row, whose rows are used in turn
+type csvFile struct {
+	columns []string
+	rows    [][]string
+	next    atomic.Uint64
+}
+
+// render returns a copy of req with its templates rendered. Only strings
+// containing "{{" are parsed as templates.
+func (t *templater) render(req *Request) (*Request, error) {
+	// Rows picked for this request, so all columns used come from the same row
+	rows := make(map[string][]string)
+
+	requestFuncs := template.FuncMap{
+		"csv": func(path, column string) (string, error) {
+			return t.csvValue(rows, path, column)
+		},
+	}
+
+	execute := func(name, text string) (string, error) {
+		if !strings.Contains(text, "{{") {
+			return text, nil
+		}
+
+		tmpl, err := t.parse(name, text)
+		if err != nil {
+			return "", err
+		}
+
+		// Cloning shares the parse tree, only the functions are per request
+		if strings.Contains(text, "csv") {
+			if tmpl, err = tmpl.Clone(); err != nil {
+				return "", fmt.Errorf("failed to render template: %w", err)
+			}
+			tmpl.Funcs(requestFuncs)
+		}
+
+		var rendered strings.Builder
+		if err := tmpl.Execute(&rendered, req); err != nil {
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+
+		return rendered.String(), nil
+	}
+
+	rendered := *req
+	var err error
+
+	if rendered.Url, err = execute("url", req.Url); err != nil {
+		return nil, err
+	}
+
+	if rendered.Body, err = execute("body", req.Body); err != nil {
+		return nil, err
+	}
+
+	if req.Headers != nil {
+		rendered.Headers = make(Headers, len(req.Headers))
+
+		for name, values := range req.Headers {
+			renderedValues := make([]string, len(values))
+
+			for i, value := range values {
+				if renderedValues[i], err = execute(name, value); err != nil {
+					return nil, err
+				}
+				if strings.ContainsAny(renderedValues[i], "\r\n\x00") {
+					return nil, fmt.Errorf("invalid value for header %s: %q", name, renderedValues[i])
+				}
+			}
+
+			rendered.Headers[name] = renderedValues
+		}
+	}
+
+	return &rendered, nil
+}
+
+func (t *templater) csvValue(rows map[string][]string, path, column string) (string, error) {
+	file, err := t.loadCSV(path)
+	if err != nil {
+		return "", err
+	}
+
+	index := slices.Index(file.columns, column)
+	if index < 0 {
+		return "", fmt.Errorf("no column %s in %s", column, path)
+	}
+
+	row, ok := rows[path]
+	if !ok {
+		row = file.rows[(file.next.Add(1)-1)%uint64(len(file.rows))]
+		rows[path] = row
+	}
+
+	return row[index], nil
+}
+
+// loadCSV reads a CSV file on first use
+func (t *templater) loadCSV(path string) (*csvFile, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if file, ok := t.csvs[path]; ok {
+		return file, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := csv.NewReader(f).ReadAll()
+	if closeErr := f.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV file %s: %w", path, err)
+	}
+
+	if len(records) < 2 {
+		return nil, fmt.Errorf("CSV file %s needs a header row and at least one row", path)
+	}
+
+	file := &csvFile{columns: records[0], rows: records[1:]}
+	t.csvs[path] = file
+	return file, nil
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var uuid [16]byte
+	_, _ = rand.Read(uuid[:])
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+// randInt returns a random integer between min and max inclusive
+func randInt(min, max int) (int, error) {
+	if max < min {
+		return 0, fmt.Errorf("randInt: max %d is less than min %d", max, min)
+	}
+	return min + mathrand.IntN(max-min+1), nil
+}
+
+// now returns the current time in RFC 3339 format, or in the given Go time
+// layout, or as Unix seconds or milliseconds for "unix" and "unixMilli"
+func now(layout ...string) (string, error) {
+	t := time.Now()
+
+	if len(layout) == 0 {
+		return t.Format(time.RFC3339), nil
+	}
+
+	if len(layout) > 1 {
+		return "", fmt.Errorf("now takes at most one layout")
+	}
+
+	switch layout[0] {
+	case "unix":
+		return fmt.Sprint(t.Unix()), nil
+	case "unixMilli":
+		return fmt.Sprint(t.UnixMilli()), nil
+	default:
+		return t.Format(layout[0]), nil
+	}
+}
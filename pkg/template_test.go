@@ -0,0 +1,180 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplaterRender(t *testing.T) {
+	users := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(users, []byte("id,name\n1,alice\n2,bob\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates := newTemplater()
+	req := &Request{
+		Method:  "POST",
+		Url:     `http://example.com/users/{{csv "` + users + `" "id"}}?r={{randInt 1 3}}&t={{now "unix"}}`,
+		Body:    `{"name": "{{csv "` + users + `" "name"}}", "id": "{{uuid}}"}`,
+		Headers: Headers{"X-Request-Id": {"{{uuid}}"}, "Accept": {"application/json"}},
+	}
+
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	urlPattern := regexp.MustCompile(`^http://example\.com/users/(\d)\?r=([1-3])&t=(\d+)$`)
+
+	for i, name := range []string{"alice", "bob", "alice"} {
+		rendered, err := templates.render(req)
+		if err != nil {
+			t.Fatalf("render() err = %v; want nil", err)
+		}
+
+		tokens := urlPattern.FindStringSubmatch(rendered.Url)
+		if tokens == nil {
+			t.Fatalf("rendered.Url = %s; want it to match %s", rendered.Url, urlPattern)
+		}
+
+		if want := strconv.Itoa(i%2 + 1); tokens[1] != want {
+			t.Errorf("rendered id = %s; want %s", tokens[1], want)
+		}
+
+		if unix, _ := strconv.ParseInt(tokens[3], 10, 64); time.Since(time.Unix(unix, 0)) > time.Minute {
+			t.Errorf("rendered now = %s; want the current time", tokens[3])
+		}
+
+		if !strings.HasPrefix(rendered.Body, `{"name": "`+name+`"`) {
+			t.Errorf("rendered.Body = %s; want name %s from the same row as the id", rendered.Body, name)
+		}
+
+		if id := rendered.Headers["X-Request-Id"][0]; !uuidPattern.MatchString(id) {
+			t.Errorf("rendered X-Request-Id = %s; want a UUID", id)
+		}
+
+		if accept := rendered.Headers["Accept"][0]; accept != "application/json" {
+			t.Errorf("rendered Accept = %s; want application/json", accept)
+		}
+	}
+
+	if !strings.Contains(req.Url, "{{") || req.Headers["X-Request-Id"][0] != "{{uuid}}" {
+		t.Errorf("render() modified the request: %+v", req)
+	}
+}
+
+func TestTemplaterParsesOnce(t *testing.T) {
+	templates := newTemplater()
+	req := &Request{Method: "GET", Url: "http://example.com/{{randInt 1 3}}", Body: "{{uuid}}"}
+
+	for i := 0; i < 3; i++ {
+		if _, err := templates.render(req); err != nil {
+			t.Fatalf("render() err = %v; want nil", err)
+		}
+	}
+
+	first, _ := templates.parse("url", req.Url)
+	second, _ := templates.parse("url", req.Url)
+	if first != second {
+		t.Errorf("parse() parsed the same template again")
+	}
+
+	parsed := 0
+	templates.parsed.Range(func(key, value any) bool {
+		parsed++
+		return true
+	})
+	if parsed != 2 {
+		t.Errorf("parsed %d templates; want 2", parsed)
+	}
+}
+
+func TestTemplaterCacheIsBounded(t *testing.T) {
+	templates := newTemplater()
+	templates.maxCached = 2
+
+	for i := 0; i < 5; i++ {
+		req := &Request{Method: "GET", Url: fmt.Sprintf("http://example.com/%d/{{uuid}}", i)}
+		if _, err := templates.render(req); err != nil {
+			t.Fatalf("render() err = %v; want nil", err)
+		}
+	}
+
+	parsed := 0
+	templates.parsed.Range(func(key, value any) bool {
+		parsed++
+		return true
+	})
+	if parsed != 2 {
+		t.Errorf("cached %d templates; want 2", parsed)
+	}
+}
+
+func TestTemplaterRenderInvalid(t *testing.T) {
+	templates := newTemplater()
+
+	urls := []string{
+		"http://example.com/{{unknown}}",
+		"http://example.com/{{randInt 5 1}}",
+		`http://example.com/{{csv "missing.csv" "id"}}`,
+		"http://example.com/{{",
+	}
+
+	for _, url := range urls {
+		if _, err := templates.render(&Request{Method: "GET", Url: url}); err == nil {
+			t.Errorf("render(%s) err = nil; want error", url)
+		}
+	}
+
+	if _, err := templates.render(&Request{Method: "GET", Url: "http://example.com", Headers: Headers{"X-Test": {"{{`a\nb`}}"}}}); err == nil {
+		t.Errorf("render() of a header with a line break err = nil; want error")
+	}
+}
+
+func TestReplayTemplates(t *testing.T) {
+	input := `{"url": "http://example.com/{{randInt 7 7}}", "method": "GET", "timestamp": "` + time.Now().Format(time.RFC3339Nano) + `"}` + "\n"
+
+	config := testConfig("1s@1", time.Second, 1, 1)
+	config.DryRun = true
+	config.Templates = true
+
+	replayer, err := New(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var urls []string
+	sink := ResultSinkFunc(func(result *Result) error {
+		urls = append(urls, result.Request.Url)
+		return nil
+	})
+
+	if _, err := replayer.Run(context.Background(), NewJSONLSource(strings.NewReader(input)), sink); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(urls) != 1 || urls[0] != "http://example.com/7" {
+		t.Errorf("urls = %v; want [http://example.com/7]", urls)
+	}
+}
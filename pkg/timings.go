@@ -0,0 +1,188 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings break the latency of a request down into its phases. DNS, Connect
+// and TLS are 0 for requests sent on a reused connection.
+type Timings struct {
+	DNS     time.Duration `json:"dns"`
+	Connect time.Duration `json:"connect"`
+	TLS     time.Duration `json:"tls"`
+	// From the start of the request to the first response byte
+	TTFB time.Duration `json:"ttfb"`
+	// From the first response byte to the end of the body
+	Transfer   time.Duration `json:"transfer"`
+	ConnReused bool          `json:"connReused"`
+}
+
+// timingTrace records the httptrace events of a request. Its hooks can be
+// called from other goroutines, even after the request completed.
+type timingTrace struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	reused       bool
+}
+
+func (t *timingTrace) withContext(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.set(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.set(&t.dnsDone) },
+		// Dialing can try several addresses, the first start and last done count
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone:          func(string, string, error) { t.set(&t.connectDone) },
+		TLSHandshakeStart:    func() { t.set(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.set(&t.tlsDone) },
+		GotFirstResponseByte: func() { t.set(&t.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+		},
+	})
+}
+
+func (t *timingTrace) set(field *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*field = time.Now()
+}
+
+// timings returns the phases of a request started at start whose body was read at end
+func (t *timingTrace) timings(start, end time.Time) *Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timings := &Timings{
+		DNS:        between(t.dnsStart, t.dnsDone),
+		Connect:    between(t.connectStart, t.connectDone),
+		TLS:        between(t.tlsStart, t.tlsDone),
+		TTFB:       between(start, t.firstByte),
+		Transfer:   between(t.firstByte, end),
+		ConnReused: t.reused,
+	}
+
+	return timings
+}
+
+// between returns 0 unless both events happened
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// TimingsSummary aggregates the timings of successful requests
+type TimingsSummary struct {
+	Requests      int     `json:"requests"`
+	ConnsReused   int     `json:"connsReused"`
+	ConnReuseRate float64 `json:"connReuseRate"`
+	// Only requests on new connections are counted in DNS, Connect and TLS,
+	// and only requests resolving a host name in DNS
+	DNS      LatencySummary `json:"dns"`
+	Connect  LatencySummary `json:"connect"`
+	TLS      LatencySummary `json:"tls"`
+	TTFB     LatencySummary `json:"ttfb"`
+	Transfer LatencySummary `json:"transfer"`
+}
+
+// timingsCollector aggregates the timings of results. It is not safe for concurrent use.
+type timingsCollector struct {
+	requests    int
+	connsReused int
+	dns         *histogram
+	connect     *histogram
+	tls         *histogram
+	ttfb        *histogram
+	transfer    *histogram
+}
+
+func newTimingsCollector() *timingsCollector {
+	return &timingsCollector{
+		dns:      newHistogram(),
+		connect:  newHistogram(),
+		tls:      newHistogram(),
+		ttfb:     newHistogram(),
+		transfer: newHistogram(),
+	}
+}
+
+func (c *timingsCollector) record(result *Result) {
+	timings := result.Timings
+	if timings == nil || result.ErrorMsg != "" {
+		return
+	}
+
+	c.requests++
+	c.ttfb.record(timings.TTFB)
+	c.transfer.record(timings.Transfer)
+
+	if timings.ConnReused {
+		c.connsReused++
+		return
+	}
+
+	if timings.DNS > 0 {
+		c.dns.record(timings.DNS)
+	}
+	if timings.Connect > 0 {
+		c.connect.record(timings.Connect)
+	}
+	if timings.TLS > 0 {
+		c.tls.record(timings.TLS)
+	}
+}
+
+// summary returns nil when no result had timings, e.g. in dry runs
+func (c *timingsCollector) summary() *TimingsSummary {
+	if c.requests == 0 {
+		return nil
+	}
+
+	return &TimingsSummary{
+		Requests:      c.requests,
+		ConnsReused:   c.connsReused,
+		ConnReuseRate: float64(c.connsReused) / float64(c.requests),
+		DNS:           latencySummary(c.dns),
+		Connect:       latencySummary(c.connect),
+		TLS:           latencySummary(c.tls),
+		TTFB:          latencySummary(c.ttfb),
+		Transfer:      latencySummary(c.transfer),
+	}
+}
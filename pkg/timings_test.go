@@ -0,0 +1,99 @@
+/*
+ripley
+Copyright (C) 2021  loveholidays
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ripley
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchTimings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	req := &Request{Method: "GET", Url: server.URL}
+
+	first, _, _ := fetch(context.Background(), client, req, time.Now())
+	if first.ErrorMsg != "" || first.Timings == nil {
+		t.Fatalf("first result = %+v; want timings", first)
+	}
+
+	if timings := first.Timings; timings.ConnReused || timings.Connect <= 0 || timings.TLS <= 0 {
+		t.Errorf("first timings = %+v; want a new connection with connect and TLS times", timings)
+	}
+
+	if timings := first.Timings; timings.TTFB < 10*time.Millisecond || timings.TTFB+timings.Transfer != first.Latency {
+		t.Errorf("first timings = %+v; want ttfb of at least 10ms adding up to latency %s with transfer", timings, first.Latency)
+	}
+
+	second, _, _ := fetch(context.Background(), client, req, time.Now())
+	if timings := second.Timings; timings == nil || !timings.ConnReused || timings.Connect != 0 || timings.TLS != 0 {
+		t.Errorf("second timings = %+v; want a reused connection", timings)
+	}
+
+	failed, _, _ := fetch(context.Background(), client, &Request{Method: "GET", Url: "http://127.0.0.1:1"}, time.Now())
+	if failed.Timings != nil {
+		t.Errorf("failed result timings = %+v; want nil", failed.Timings)
+	}
+}
+
+func TestTimingsCollector(t *testing.T) {
+	collector := newSummaryCollector()
+	req := &Request{Url: "http://example.com/"}
+
+	collector.record(&Result{StatusCode: 200, Request: req})
+	if timings := collector.summary(time.Second, 1).Timings; timings != nil {
+		t.Errorf("summary.Timings = %+v; want nil without timings", timings)
+	}
+
+	collector.record(&Result{StatusCode: 200, Request: req, Timings: &Timings{DNS: 2 * time.Millisecond, Connect: 4 * time.Millisecond, TTFB: 10 * time.Millisecond}})
+	collector.record(&Result{StatusCode: 200, Request: req, Timings: &Timings{TTFB: 6 * time.Millisecond, ConnReused: true}})
+	collector.record(&Result{ErrorMsg: "timeout", Request: req, Timings: &Timings{TTFB: time.Second}})
+
+	timings := collector.summary(time.Second, 1).Timings
+	if timings == nil {
+		t.Fatalf("summary.Timings = nil; want timings")
+	}
+
+	if timings.Requests != 2 || timings.ConnsReused != 1 || timings.ConnReuseRate != 0.5 {
+		t.Errorf("summary.Timings = %d reused of %d (%v); want 1 of 2 (0.5)", timings.ConnsReused, timings.Requests, timings.ConnReuseRate)
+	}
+
+	if timings.Connect.Max != 4*time.Millisecond || timings.TTFB.Max != 10*time.Millisecond || timings.TLS.Max != 0 {
+		t.Errorf("summary.Timings = %+v; want connect 4ms, ttfb 10ms and no TLS", timings)
+	}
+
+	var buffer bytes.Buffer
+	if err := collector.summary(time.Second, 1).WriteText(&buffer); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(buffer.String(), "Connections reused:  1 of 2 (50.00%)") {
+		t.Errorf("WriteText() = %s; want connections reused", buffer.String())
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/loveholidays/ripley/pkg/convert"
+	"github.com/loveholidays/ripley/tools/accesslogxripley/pkg/accesslog"
+	"github.com/loveholidays/ripley/tools/accesslogxripley/pkg/converter"
+)
+
+func main() {
+	var options convert.Options
+	options.RegisterFlags(flag.CommandLine)
+	format := flag.String("format", "combined", `Log format, "combined", "common" or an nginx log_format template`)
+	help := flag.Bool("help", false, "Show usage information")
+	flag.Parse()
+
+	if *help {
+		fmt.Fprintf(os.Stderr, "accesslogxripley - Convert nginx/Apache access logs to Ripley format\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: accesslogxripley [options] < access.log > output.jsonl\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  cat access.log | accesslogxripley -host localhost:8080 > ripley.jsonl\n")
+		fmt.Fprintf(os.Stderr, "  cat access.log | accesslogxripley -format common -host localhost:8443 -https > ripley.jsonl\n")
+		fmt.Fprintf(os.Stderr, "  cat access.log | accesslogxripley -format '$host [$time_iso8601] \"$request\" $status' > ripley.jsonl\n\n")
+		return
+	}
+
+	parser, err := accesslog.NewParser(*format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stats, err := convert.Run(os.Stdin, os.Stdout, converter.NewWithParser(parser), options)
+
+	if reportErr := stats.Report(os.Stderr); reportErr != nil {
+		log.Printf("failed to write report: %v", reportErr)
+	}
+
+	if err != nil {
+		log.Fatalf("error converting input: %v", err)
+	}
+}
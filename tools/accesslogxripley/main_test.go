@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCLIIntegration(t *testing.T) {
+	input, err := os.ReadFile("testdata/access.log")
+	if err != nil {
+		t.Fatalf("failed to read sample log: %v", err)
+	}
+
+	output := runCLICommand(t, []string{"-host", "localhost:8080"}, string(input)+"malformed line\n")
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 output lines, got %d", len(lines))
+	}
+
+	result := parseJSONOutput(t, lines[0])
+	assertFieldEquals(t, result, "method", "GET")
+	assertFieldEquals(t, result, "url", "http://localhost:8080/api/v1/data?id=12345")
+	assertFieldEquals(t, result, "timestamp", "2025-09-03T15:30:32Z")
+}
+
+func TestCLICustomFormat(t *testing.T) {
+	input := `api.test.com [2025-09-03T15:30:32+00:00] "GET /x HTTP/1.1" 200` + "\n"
+	output := runCLICommand(t, []string{"-format", `$host [$time_iso8601] "$request" $status`, "-https"}, input)
+
+	result := parseJSONOutput(t, output)
+	assertFieldEquals(t, result, "url", "https://api.test.com/x")
+}
+
+func runCLICommand(t *testing.T, args []string, input string) string {
+	cmdArgs := append([]string{"run", "main.go"}, args...)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Dir = "."
+	cmd.Stdin = strings.NewReader(input)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nstderr: %s", err, stderr.String())
+	}
+
+	return stdout.String()
+}
+
+func parseJSONOutput(t *testing.T, output string) map[string]interface{} {
+	var result map[string]interface{}
+	err := json.Unmarshal([]byte(strings.TrimSpace(output)), &result)
+	if err != nil {
+		t.Fatalf("failed to parse output JSON: %v", err)
+	}
+	return result
+}
+
+func assertFieldEquals(t *testing.T, result map[string]interface{}, field, expected string) {
+	if result[field] != expected {
+		t.Errorf("expected %s %s, got %v", field, expected, result[field])
+	}
+}
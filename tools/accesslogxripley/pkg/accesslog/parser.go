@@ -0,0 +1,184 @@
+package accesslog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Predefined formats, identical for nginx and Apache
+const (
+	CommonFormat   = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent`
+	CombinedFormat = CommonFormat + ` "$http_referer" "$http_user_agent"`
+)
+
+const timeLocalLayout = "02/Jan/2006:15:04:05 -0700"
+
+// Entry holds the request fields of an access log line
+type Entry struct {
+	Time      time.Time
+	Method    string
+	URI       string
+	Scheme    string
+	Host      string
+	UserAgent string
+	Referer   string
+	Status    int
+}
+
+// Parser parses access log lines written with an nginx log_format template
+type Parser struct {
+	pattern   *regexp.Regexp
+	variables []string
+}
+
+var variablePattern = regexp.MustCompile(`\$([a-z0-9_]+)`)
+
+// NewParser accepts "combined", "common" or a custom nginx log_format template,
+// e.g. `$remote_addr [$time_local] "$request" $status "$http_user_agent"`
+func NewParser(format string) (*Parser, error) {
+	switch format {
+	case "combined":
+		format = CombinedFormat
+	case "common":
+		format = CommonFormat
+	}
+
+	var pattern strings.Builder
+	var variables []string
+	pattern.WriteString("^")
+
+	last := 0
+	for _, match := range variablePattern.FindAllStringSubmatchIndex(format, -1) {
+		pattern.WriteString(regexp.QuoteMeta(format[last:match[0]]))
+		// Variables are matched lazily up to the literal text that follows them
+		pattern.WriteString("(.*?)")
+		variables = append(variables, format[match[2]:match[3]])
+		last = match[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(format[last:]))
+	pattern.WriteString("$")
+
+	if !hasTime(variables) {
+		return nil, fmt.Errorf("log format must contain $time_local, $time_iso8601 or $msec: %s", format)
+	}
+
+	if !hasRequest(variables) {
+		return nil, fmt.Errorf("log format must contain $request or $request_method and $request_uri: %s", format)
+	}
+
+	compiled, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid log format %s: %w", format, err)
+	}
+
+	return &Parser{pattern: compiled, variables: variables}, nil
+}
+
+func hasTime(variables []string) bool {
+	for _, v := range variables {
+		if v == "time_local" || v == "time_iso8601" || v == "msec" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRequest(variables []string) bool {
+	method, uri := false, false
+	for _, v := range variables {
+		switch v {
+		case "request":
+			return true
+		case "request_method":
+			method = true
+		case "request_uri":
+			uri = true
+		}
+	}
+	return method && uri
+}
+
+// Parse extracts an Entry from a single log line
+func (p *Parser) Parse(line string) (Entry, error) {
+	var entry Entry
+
+	match := p.pattern.FindStringSubmatch(line)
+	if match == nil {
+		return entry, fmt.Errorf("line does not match log format")
+	}
+
+	for i, variable := range p.variables {
+		value := match[i+1]
+		if value == "-" {
+			value = ""
+		}
+
+		if err := entry.set(variable, value); err != nil {
+			return entry, err
+		}
+	}
+
+	if entry.Time.IsZero() {
+		return entry, fmt.Errorf("missing time")
+	}
+
+	if entry.Method == "" || entry.URI == "" {
+		return entry, fmt.Errorf("missing request method or URI")
+	}
+
+	return entry, nil
+}
+
+func (e *Entry) set(variable, value string) error {
+	var err error
+
+	switch variable {
+	case "time_local":
+		e.Time, err = time.Parse(timeLocalLayout, value)
+	case "time_iso8601":
+		e.Time, err = time.Parse(time.RFC3339, value)
+	case "msec":
+		e.Time, err = parseMsec(value)
+	case "request":
+		// e.g. "GET /path?query HTTP/1.1"
+		fields := strings.Fields(value)
+		if len(fields) < 2 {
+			return fmt.Errorf("invalid request: %s", value)
+		}
+		e.Method, e.URI = fields[0], fields[1]
+	case "request_method":
+		e.Method = value
+	case "request_uri":
+		e.URI = value
+	case "scheme":
+		e.Scheme = value
+	case "host", "http_host":
+		e.Host = value
+	case "http_user_agent":
+		e.UserAgent = value
+	case "http_referer":
+		e.Referer = value
+	case "status":
+		if value != "" {
+			e.Status, err = strconv.Atoi(value)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid $%s: %w", variable, err)
+	}
+
+	return nil
+}
+
+// parseMsec parses seconds since the epoch with millisecond resolution, e.g. 1756913432.928
+func parseMsec(value string) (time.Time, error) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(int64(seconds*1000 + 0.5)).UTC(), nil
+}
@@ -0,0 +1,143 @@
+package accesslog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParser_ParseCombined(t *testing.T) {
+	parser, err := NewParser("combined")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entry, err := parser.Parse(`192.168.1.100 - - [03/Sep/2025:16:30:32 +0100] "GET /api/v1/data?id=12345 HTTP/1.1" 200 512 "https://www.test.com/" "Mozilla/5.0 (X11; Linux x86_64)"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := Entry{
+		Time:      time.Date(2025, 9, 3, 15, 30, 32, 0, time.UTC),
+		Method:    "GET",
+		URI:       "/api/v1/data?id=12345",
+		UserAgent: "Mozilla/5.0 (X11; Linux x86_64)",
+		Referer:   "https://www.test.com/",
+		Status:    200,
+	}
+	assertEntry(t, entry, expected)
+}
+
+func TestParser_ParseCommon(t *testing.T) {
+	parser, err := NewParser("common")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entry, err := parser.Parse(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := Entry{
+		Time:   time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC),
+		Method: "GET",
+		URI:    "/apache_pb.gif",
+		Status: 200,
+	}
+	assertEntry(t, entry, expected)
+}
+
+func TestParser_ParseCustomFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		line     string
+		expected Entry
+	}{
+		{
+			name:   "host, scheme and ISO 8601 time",
+			format: `$remote_addr $host $scheme [$time_iso8601] "$request" $status "$http_user_agent"`,
+			line:   `10.0.0.1 api.test.com https [2025-09-03T15:30:32+00:00] "DELETE /items/1 HTTP/2.0" 204 "-"`,
+			expected: Entry{
+				Time:   time.Date(2025, 9, 3, 15, 30, 32, 0, time.UTC),
+				Method: "DELETE",
+				URI:    "/items/1",
+				Scheme: "https",
+				Host:   "api.test.com",
+				Status: 204,
+			},
+		},
+		{
+			name:   "msec time and separate method and URI",
+			format: `$msec $request_method $request_uri $http_host`,
+			line:   `1756913432.928 PUT /items/2 api.test.com:8080`,
+			expected: Entry{
+				Time:   time.Date(2025, 9, 3, 15, 30, 32, 928000000, time.UTC),
+				Method: "PUT",
+				URI:    "/items/2",
+				Host:   "api.test.com:8080",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParser(tt.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			entry, err := parser.Parse(tt.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertEntry(t, entry, tt.expected)
+		})
+	}
+}
+
+func TestNewParser_InvalidFormat(t *testing.T) {
+	formats := []string{
+		`$remote_addr "$request"`,
+		`[$time_local] $request_method $status`,
+	}
+
+	for _, format := range formats {
+		if _, err := NewParser(format); err == nil {
+			t.Errorf("expected error for format %s but got none", format)
+		}
+	}
+}
+
+func TestParser_ParseInvalidLine(t *testing.T) {
+	parser, err := NewParser("combined")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := []string{
+		`not an access log line`,
+		`127.0.0.1 - - [yesterday] "GET / HTTP/1.1" 200 1 "-" "-"`,
+		`127.0.0.1 - - [03/Sep/2025:15:30:32 +0000] "-" 400 0 "-" "-"`,
+	}
+
+	for _, line := range lines {
+		if _, err := parser.Parse(line); err == nil {
+			t.Errorf("expected error for line %s but got none", line)
+		}
+	}
+}
+
+func assertEntry(t *testing.T, entry, expected Entry) {
+	t.Helper()
+
+	if !entry.Time.Equal(expected.Time) {
+		t.Errorf("Time: got %v, want %v", entry.Time, expected.Time)
+	}
+
+	entry.Time, expected.Time = time.Time{}, time.Time{}
+	if entry != expected {
+		t.Errorf("got %+v, want %+v", entry, expected)
+	}
+}
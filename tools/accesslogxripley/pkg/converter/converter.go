@@ -0,0 +1,106 @@
+package converter
+
+import (
+	"fmt"
+	"net/url"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
+	"github.com/loveholidays/ripley/tools/accesslogxripley/pkg/accesslog"
+)
+
+type Converter struct {
+	parser *accesslog.Parser
+}
+
+var _ convert.Converter = (*Converter)(nil)
+
+// New returns a converter of logs in the combined format
+func New() *Converter {
+	parser, err := accesslog.NewParser("combined")
+	if err != nil {
+		panic(err)
+	}
+	return NewWithParser(parser)
+}
+
+// NewWithParser returns a converter of logs in the format parsed by parser
+func NewWithParser(parser *accesslog.Parser) *Converter {
+	return &Converter{parser: parser}
+}
+
+// Convert parses and converts a single access log line
+func (c *Converter) Convert(line []byte, options convert.Options) (*ripley.Request, error) {
+	entry, err := c.parser.Parse(string(line))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.convertEntry(entry, options)
+}
+
+func (c *Converter) ConvertToRipley(entry accesslog.Entry, newHost string, upgradeHTTPS bool) (*ripley.Request, error) {
+	return c.convertEntry(entry, convert.Options{Host: newHost, HTTPS: upgradeHTTPS})
+}
+
+func (c *Converter) convertEntry(entry accesslog.Entry, options convert.Options) (*ripley.Request, error) {
+	targetURL, err := c.buildTargetURL(entry, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build target URL: %w", err)
+	}
+
+	ripleyReq := &ripley.Request{
+		Method:    entry.Method,
+		Url:       targetURL,
+		Timestamp: entry.Time,
+		Headers:   c.buildHeaders(entry),
+	}
+
+	return ripleyReq, nil
+}
+
+func (c *Converter) buildTargetURL(entry accesslog.Entry, options convert.Options) (string, error) {
+	parsedURL, err := url.Parse(entry.URI)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URI %s: %w", entry.URI, err)
+	}
+
+	// Access logs usually record the path only
+	if parsedURL.Host == "" {
+		parsedURL.Host = entry.Host
+	}
+
+	if parsedURL.Scheme == "" {
+		parsedURL.Scheme = entry.Scheme
+	}
+
+	if parsedURL.Scheme == "" {
+		parsedURL.Scheme = "http"
+	}
+
+	parsedURL.Host = options.MapHost(parsedURL.Host)
+
+	if parsedURL.Host == "" {
+		return "", fmt.Errorf("no host for URI %s, log $host or set a target host", entry.URI)
+	}
+
+	if options.HTTPS && parsedURL.Scheme == "http" {
+		parsedURL.Scheme = "https"
+	}
+
+	return parsedURL.String(), nil
+}
+
+func (c *Converter) buildHeaders(entry accesslog.Entry) ripley.Headers {
+	headers := make(ripley.Headers)
+
+	if entry.UserAgent != "" {
+		headers["User-Agent"] = []string{entry.UserAgent}
+	}
+
+	if entry.Referer != "" {
+		headers["Referer"] = []string{entry.Referer}
+	}
+
+	return headers
+}
@@ -0,0 +1,129 @@
+package converter
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
+	"github.com/loveholidays/ripley/tools/accesslogxripley/pkg/accesslog"
+)
+
+func TestConverter_ConvertToRipley(t *testing.T) {
+	conv := New()
+	timestamp := time.Date(2025, 9, 3, 15, 30, 32, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		entry        accesslog.Entry
+		newHost      string
+		upgradeHTTPS bool
+		expected     *ripley.Request
+	}{
+		{
+			name:    "path only with host change",
+			entry:   accesslog.Entry{Time: timestamp, Method: "GET", URI: "/api/v1/data?id=12345", UserAgent: "TestBrowser/1.0", Referer: "https://www.test.com/"},
+			newHost: "localhost:8080",
+			expected: &ripley.Request{
+				Method:    "GET",
+				Url:       "http://localhost:8080/api/v1/data?id=12345",
+				Timestamp: timestamp,
+				Headers: ripley.Headers{
+					"User-Agent": {"TestBrowser/1.0"},
+					"Referer":    {"https://www.test.com/"},
+				},
+			},
+		},
+		{
+			name:  "logged host and scheme",
+			entry: accesslog.Entry{Time: timestamp, Method: "POST", URI: "/create", Host: "api.test.com", Scheme: "https"},
+			expected: &ripley.Request{
+				Method:    "POST",
+				Url:       "https://api.test.com/create",
+				Timestamp: timestamp,
+				Headers:   ripley.Headers{},
+			},
+		},
+		{
+			name:         "HTTPS upgrade with host change",
+			entry:        accesslog.Entry{Time: timestamp, Method: "GET", URI: "/secure", Host: "api.test.com"},
+			newHost:      "localhost:8443",
+			upgradeHTTPS: true,
+			expected: &ripley.Request{
+				Method:    "GET",
+				Url:       "https://localhost:8443/secure",
+				Timestamp: timestamp,
+				Headers:   ripley.Headers{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := conv.ConvertToRipley(tt.entry, tt.newHost, tt.upgradeHTTPS)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertConversionResult(t, result, tt.expected)
+		})
+	}
+}
+
+func TestConverter_ConvertToRipleyWithoutHost(t *testing.T) {
+	conv := New()
+	entry := accesslog.Entry{Time: time.Now(), Method: "GET", URI: "/api"}
+
+	if _, err := conv.ConvertToRipley(entry, "", false); err == nil {
+		t.Errorf("expected error but got none")
+	}
+}
+
+func assertConversionResult(t *testing.T, result, expected *ripley.Request) {
+	if result.Method != expected.Method {
+		t.Errorf("Method: got %s, want %s", result.Method, expected.Method)
+	}
+
+	if result.Url != expected.Url {
+		t.Errorf("URL: got %s, want %s", result.Url, expected.Url)
+	}
+
+	if !result.Timestamp.Equal(expected.Timestamp) {
+		t.Errorf("Timestamp: got %v, want %v", result.Timestamp, expected.Timestamp)
+	}
+
+	if len(result.Headers) != len(expected.Headers) {
+		t.Errorf("Headers: got %v, want %v", result.Headers, expected.Headers)
+		return
+	}
+
+	for k, v := range expected.Headers {
+		if !slices.Equal(result.Headers[k], v) {
+			t.Errorf("Header %s: got %v, want %v", k, result.Headers[k], v)
+		}
+	}
+}
+
+func TestConverter_Convert(t *testing.T) {
+	parser, err := accesslog.NewParser("common")
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+
+	conv := NewWithParser(parser)
+	line := []byte(`10.0.0.1 - - [10/Oct/2023:13:55:36 +0000] "GET /api/users?id=1 HTTP/1.1" 200 512`)
+	options := convert.Options{Host: "localhost:8080", HTTPS: true}
+
+	result, err := conv.Convert(line, options)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	if result.Url != "https://localhost:8080/api/users?id=1" {
+		t.Errorf("Url = %v; want https://localhost:8080/api/users?id=1", result.Url)
+	}
+
+	if _, err := conv.Convert([]byte("not an access log line"), options); err == nil {
+		t.Errorf("Convert() of an invalid line error = nil; want error")
+	}
+}
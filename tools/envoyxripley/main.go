@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/loveholidays/ripley/pkg/convert"
+	"github.com/loveholidays/ripley/tools/envoyxripley/pkg/converter"
+)
+
+func main() {
+	var options convert.Options
+	options.RegisterFlags(flag.CommandLine)
+	help := flag.Bool("help", false, "Show usage information")
+	flag.Parse()
+
+	if *help {
+		fmt.Fprintf(os.Stderr, "envoyxripley - Convert Envoy/Istio access logs to Ripley format\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: envoyxripley [options] < input.log > output.jsonl\n\n")
+		fmt.Fprintf(os.Stderr, "Both the default text format and the JSON format are accepted.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  kubectl logs deploy/your-app -c istio-proxy | envoyxripley -host localhost:8080 > ripley.jsonl\n")
+		fmt.Fprintf(os.Stderr, "  cat envoy.log | envoyxripley -host localhost:8443 -https > ripley.jsonl\n\n")
+		return
+	}
+
+	stats, err := convert.Run(os.Stdin, os.Stdout, converter.New(), options)
+
+	if reportErr := stats.Report(os.Stderr); reportErr != nil {
+		log.Printf("failed to write report: %v", reportErr)
+	}
+
+	if err != nil {
+		log.Fatalf("error converting input: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCLIIntegration(t *testing.T) {
+	input, err := os.ReadFile("testdata/envoy_sample.log")
+	if err != nil {
+		t.Fatalf("failed to read sample log: %v", err)
+	}
+
+	t.Run("basic conversion without host change", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(runCLICommand(t, []string{}, string(input))), "\n")
+
+		result := parseJSONOutput(t, lines[0])
+		assertFieldEquals(t, result, "method", "GET")
+		assertFieldEquals(t, result, "url", "http://api-service.test.svc.cluster.local/api/v1/data?id=12345")
+		assertFieldEquals(t, result, "timestamp", "2025-09-03T15:30:32.928Z")
+	})
+
+	t.Run("text and JSON lines with host change", func(t *testing.T) {
+		output := runCLICommand(t, []string{"-host", "staging.test.com:9000", "-https"}, string(input))
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("expected 3 output lines, got %d", len(lines))
+		}
+
+		expectedURLs := []string{
+			"https://staging.test.com:9000/api/v1/data?id=12345",
+			"https://staging.test.com:9000/api/v1/create",
+			"https://staging.test.com:9000/api/v1/items/7",
+		}
+
+		for i, line := range lines {
+			assertFieldEquals(t, parseJSONOutput(t, line), "url", expectedURLs[i])
+		}
+	})
+}
+
+func runCLICommand(t *testing.T, args []string, input string) string {
+	cmdArgs := append([]string{"run", "main.go"}, args...)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Dir = "."
+	cmd.Stdin = strings.NewReader(input)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nstderr: %s", err, stderr.String())
+	}
+
+	return stdout.String()
+}
+
+func parseJSONOutput(t *testing.T, output string) map[string]interface{} {
+	var result map[string]interface{}
+	err := json.Unmarshal([]byte(strings.TrimSpace(output)), &result)
+	if err != nil {
+		t.Fatalf("failed to parse output JSON: %v", err)
+	}
+	return result
+}
+
+func assertFieldEquals(t *testing.T, result map[string]interface{}, field, expected string) {
+	if result[field] != expected {
+		t.Errorf("expected %s %s, got %v", field, expected, result[field])
+	}
+}
@@ -0,0 +1,93 @@
+package converter
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
+	"github.com/loveholidays/ripley/tools/envoyxripley/pkg/envoy"
+)
+
+type Converter struct{}
+
+var _ convert.Converter = (*Converter)(nil)
+
+func New() *Converter {
+	return &Converter{}
+}
+
+// Convert parses and converts a single access log line, in text or JSON format
+func (c *Converter) Convert(line []byte, options convert.Options) (*ripley.Request, error) {
+	envoyReq, err := envoy.ParseLine(string(line))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.convertRequest(envoyReq, options)
+}
+
+func (c *Converter) ConvertToRipley(envoy envoy.Request, newHost string, upgradeHTTPS bool) (*ripley.Request, error) {
+	return c.convertRequest(envoy, convert.Options{Host: newHost, HTTPS: upgradeHTTPS})
+}
+
+func (c *Converter) convertRequest(envoy envoy.Request, options convert.Options) (*ripley.Request, error) {
+	timestamp, err := c.parseTimestamp(envoy.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse start time %s: %w", envoy.StartTime, err)
+	}
+
+	targetURL, err := c.buildTargetURL(envoy.Authority, envoy.Path, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build target URL: %w", err)
+	}
+
+	ripleyReq := &ripley.Request{
+		Method:    envoy.Method,
+		Url:       targetURL,
+		Timestamp: timestamp,
+		Headers:   c.buildHeaders(envoy),
+	}
+
+	return ripleyReq, nil
+}
+
+func (c *Converter) parseTimestamp(timestampStr string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, timestampStr)
+}
+
+func (c *Converter) buildTargetURL(authority, path string, options convert.Options) (string, error) {
+	parsedURL, err := url.Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse path %s: %w", path, err)
+	}
+
+	// Envoy does not log the scheme
+	parsedURL.Scheme = "http"
+	parsedURL.Host = options.MapHost(authority)
+
+	if parsedURL.Host == "" {
+		return "", fmt.Errorf("no authority for path %s", path)
+	}
+
+	if options.HTTPS {
+		parsedURL.Scheme = "https"
+	}
+
+	return parsedURL.String(), nil
+}
+
+func (c *Converter) buildHeaders(envoy envoy.Request) ripley.Headers {
+	headers := make(ripley.Headers)
+
+	if envoy.UserAgent != "" {
+		headers["User-Agent"] = []string{envoy.UserAgent}
+	}
+
+	if envoy.XForwardedFor != "" {
+		headers["X-Forwarded-For"] = []string{envoy.XForwardedFor}
+	}
+
+	return headers
+}
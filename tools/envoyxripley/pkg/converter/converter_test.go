@@ -0,0 +1,330 @@
+package converter
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
+	"github.com/loveholidays/ripley/tools/envoyxripley/pkg/envoy"
+)
+
+func TestConverter_ConvertToRipley(t *testing.T) {
+	t.Run("successful conversions", func(t *testing.T) {
+		testSuccessfulConversions(t)
+	})
+
+	t.Run("error cases", func(t *testing.T) {
+		testConversionErrors(t)
+	})
+}
+
+func testSuccessfulConversions(t *testing.T) {
+	t.Run("basic conversion tests", func(t *testing.T) {
+		testBasicConversions(t)
+	})
+
+	t.Run("HTTPS upgrade tests", func(t *testing.T) {
+		testHTTPSUpgradeConversions(t)
+	})
+}
+
+func testBasicConversions(t *testing.T) {
+	conv := New()
+
+	tests := []conversionTest{
+		{
+			name:     "basic conversion without host change",
+			envoyReq: createFullEnvoyRequest(),
+			newHost:  "",
+			expected: &ripley.Request{
+				Method:    "GET",
+				Url:       "http://api-service.test.svc.cluster.local/api/v1/data?id=12345",
+				Timestamp: mustParseTime("2025-09-03T15:30:32.928Z"),
+				Headers: ripley.Headers{
+					"User-Agent":      {"TestClient/1.0"},
+					"X-Forwarded-For": {"192.168.1.100"},
+				},
+			},
+		},
+		{
+			name:     "conversion with host change",
+			envoyReq: createSimpleEnvoyRequest("POST", "api.test.com", "/endpoint", "TestAgent/1.0"),
+			newHost:  "localhost:8080",
+			expected: &ripley.Request{
+				Method:    "POST",
+				Url:       "http://localhost:8080/endpoint",
+				Timestamp: mustParseTime("2025-09-03T15:30:32.928Z"),
+				Headers: ripley.Headers{
+					"User-Agent": {"TestAgent/1.0"},
+				},
+			},
+		},
+		{
+			name:     "conversion without user agent",
+			envoyReq: createSimpleEnvoyRequest("GET", "api.test.com", "/endpoint", ""),
+			newHost:  "",
+			expected: &ripley.Request{
+				Method:    "GET",
+				Url:       "http://api.test.com/endpoint",
+				Timestamp: mustParseTime("2025-09-03T15:30:32.928Z"),
+				Headers:   ripley.Headers{},
+			},
+		},
+		{
+			name:     "conversion with complex query parameters",
+			envoyReq: createSimpleEnvoyRequest("GET", "search-api.test.com", "/search?q=test&category=books&page=1&limit=10&sort=price", "TestBrowser/2.0"),
+			newHost:  "staging.search-api.test.com:9000",
+			expected: &ripley.Request{
+				Method:    "GET",
+				Url:       "http://staging.search-api.test.com:9000/search?q=test&category=books&page=1&limit=10&sort=price",
+				Timestamp: mustParseTime("2025-09-03T15:30:32.928Z"),
+				Headers: ripley.Headers{
+					"User-Agent": {"TestBrowser/2.0"},
+				},
+			},
+		},
+	}
+
+	runConversionTests(t, conv, tests)
+}
+
+func testHTTPSUpgradeConversions(t *testing.T) {
+	conv := New()
+
+	tests := []conversionTest{
+		{
+			name:         "HTTP to HTTPS upgrade without host change",
+			envoyReq:     createSimpleEnvoyRequest("GET", "api.test.com", "/secure-endpoint", "TestAgent/1.0"),
+			newHost:      "",
+			upgradeHTTPS: true,
+			expected: &ripley.Request{
+				Method:    "GET",
+				Url:       "https://api.test.com/secure-endpoint",
+				Timestamp: mustParseTime("2025-09-03T15:30:32.928Z"),
+				Headers: ripley.Headers{
+					"User-Agent": {"TestAgent/1.0"},
+				},
+			},
+		},
+		{
+			name:         "HTTP to HTTPS upgrade with host change",
+			envoyReq:     createSimpleEnvoyRequest("GET", "api.test.com", "/secure-endpoint", "TestAgent/1.0"),
+			newHost:      "localhost:8443",
+			upgradeHTTPS: true,
+			expected: &ripley.Request{
+				Method:    "GET",
+				Url:       "https://localhost:8443/secure-endpoint",
+				Timestamp: mustParseTime("2025-09-03T15:30:32.928Z"),
+				Headers: ripley.Headers{
+					"User-Agent": {"TestAgent/1.0"},
+				},
+			},
+		},
+	}
+
+	runConversionTests(t, conv, tests)
+}
+
+type conversionTest struct {
+	name         string
+	envoyReq     envoy.Request
+	newHost      string
+	upgradeHTTPS bool
+	expected     *ripley.Request
+}
+
+func createFullEnvoyRequest() envoy.Request {
+	return envoy.Request{
+		StartTime:     "2025-09-03T15:30:32.928Z",
+		Method:        "GET",
+		Path:          "/api/v1/data?id=12345",
+		Protocol:      "HTTP/1.1",
+		ResponseCode:  200,
+		Duration:      35,
+		XForwardedFor: "192.168.1.100",
+		UserAgent:     "TestClient/1.0",
+		RequestID:     "cc21d9b0-cf5c-432b-8c7e-98aeb7988cd2",
+		Authority:     "api-service.test.svc.cluster.local",
+		UpstreamHost:  "10.0.2.1:8080",
+	}
+}
+
+func createSimpleEnvoyRequest(method, authority, path, userAgent string) envoy.Request {
+	return envoy.Request{
+		StartTime: "2025-09-03T15:30:32.928Z",
+		Method:    method,
+		Path:      path,
+		Authority: authority,
+		UserAgent: userAgent,
+	}
+}
+
+func runConversionTests(t *testing.T, conv *Converter, tests []conversionTest) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := conv.ConvertToRipley(tt.envoyReq, tt.newHost, tt.upgradeHTTPS)
+			assertNoError(t, err)
+			assertConversionResult(t, result, tt.expected)
+		})
+	}
+}
+
+func testConversionErrors(t *testing.T) {
+	conv := New()
+
+	tests := []struct {
+		name     string
+		envoyReq envoy.Request
+		newHost  string
+	}{
+		{
+			name:     "conversion with invalid start time",
+			envoyReq: envoy.Request{Method: "GET", StartTime: "invalid-timestamp", Path: "/endpoint", Authority: "api.test.com"},
+			newHost:  "",
+		},
+		{
+			name:     "conversion with invalid path and new host",
+			envoyReq: envoy.Request{Method: "GET", StartTime: "2025-09-03T15:30:32.928Z", Path: "://invalid-path"},
+			newHost:  "localhost:8080",
+		},
+		{
+			name:     "conversion without authority or new host",
+			envoyReq: envoy.Request{Method: "GET", StartTime: "2025-09-03T15:30:32.928Z", Path: "/endpoint"},
+			newHost:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := conv.ConvertToRipley(tt.envoyReq, tt.newHost, false)
+			assertError(t, err)
+		})
+	}
+}
+
+func assertNoError(t *testing.T, err error) {
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func assertError(t *testing.T, err error) {
+	if err == nil {
+		t.Errorf("expected error but got none")
+	}
+}
+
+func assertConversionResult(t *testing.T, result, expected *ripley.Request) {
+	if result.Method != expected.Method {
+		t.Errorf("Method: got %s, want %s", result.Method, expected.Method)
+	}
+
+	if result.Url != expected.Url {
+		t.Errorf("URL: got %s, want %s", result.Url, expected.Url)
+	}
+
+	if !result.Timestamp.Equal(expected.Timestamp) {
+		t.Errorf("Timestamp: got %v, want %v", result.Timestamp, expected.Timestamp)
+	}
+
+	assertHeadersMatch(t, result.Headers, expected.Headers)
+}
+
+func assertHeadersMatch(t *testing.T, result, expected ripley.Headers) {
+	if len(result) != len(expected) {
+		t.Errorf("Headers length: got %d, want %d", len(result), len(expected))
+		return
+	}
+
+	for k, v := range expected {
+		if !slices.Equal(result[k], v) {
+			t.Errorf("Header %s: got %v, want %v", k, result[k], v)
+		}
+	}
+}
+
+func TestConverter_parseTimestamp(t *testing.T) {
+	conv := New()
+
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+	}{
+		{name: "valid timestamp with milliseconds", input: "2025-09-03T15:30:32.928Z"},
+		{name: "valid timestamp without fraction", input: "2025-09-03T15:30:32Z"},
+		{name: "invalid timestamp", input: "invalid", expectError: true},
+		{name: "empty timestamp", input: "", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := conv.parseTimestamp(tt.input)
+			if tt.expectError {
+				assertError(t, err)
+			} else {
+				assertNoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConverter_buildTargetURL(t *testing.T) {
+	conv := New()
+
+	tests := []struct {
+		name         string
+		authority    string
+		path         string
+		newHost      string
+		upgradeHTTPS bool
+		expected     string
+	}{
+		{name: "authority only", authority: "api.test.com", path: "/test", expected: "http://api.test.com/test"},
+		{name: "new host with port", authority: "api.test.com", path: "/test?a=1", newHost: "localhost:8080", expected: "http://localhost:8080/test?a=1"},
+		{name: "HTTPS upgrade", authority: "api.test.com:80", path: "/", upgradeHTTPS: true, expected: "https://api.test.com:80/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := conv.buildTargetURL(tt.authority, tt.path, convert.Options{Host: tt.newHost, HTTPS: tt.upgradeHTTPS})
+			assertNoError(t, err)
+
+			if result != tt.expected {
+				t.Errorf("got %s, want %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func mustParseTime(timeStr string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, timeStr)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestConverter_Convert(t *testing.T) {
+	conv := New()
+	line := []byte(`{"start_time":"2023-10-10T13:55:36.123Z","method":"GET","path":"/api/users","authority":"api.example.com","response_code":200}`)
+	options := convert.Options{
+		Host:    "localhost:8080",
+		HostMap: map[string]string{"api.example.com": "staging:8080"},
+	}
+
+	result, err := conv.Convert(line, options)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	if result.Url != "http://staging:8080/api/users" {
+		t.Errorf("Url = %v; want http://staging:8080/api/users", result.Url)
+	}
+
+	if _, err := conv.Convert([]byte("not an access log line"), options); err == nil {
+		t.Errorf("Convert() of an invalid line error = nil; want error")
+	}
+}
@@ -0,0 +1,95 @@
+package envoy
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Request holds the fields of an Envoy access log entry, with the JSON keys
+// of Istio's JSON access log encoding
+type Request struct {
+	StartTime     string `json:"start_time"`
+	Method        string `json:"method"`
+	Path          string `json:"path"`
+	Protocol      string `json:"protocol"`
+	ResponseCode  int    `json:"response_code"`
+	Duration      int    `json:"duration"`
+	XForwardedFor string `json:"x_forwarded_for"`
+	UserAgent     string `json:"user_agent"`
+	RequestID     string `json:"request_id"`
+	Authority     string `json:"authority"`
+	UpstreamHost  string `json:"upstream_host"`
+}
+
+// Envoy's default format and Istio's default text format both start with
+//
+//	[%START_TIME%] "%REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH)% %PROTOCOL%" %RESPONSE_CODE%
+//
+// and contain
+//
+//	"%REQ(X-FORWARDED-FOR)%" "%REQ(USER-AGENT)%" "%REQ(X-REQUEST-ID)%" "%REQ(:AUTHORITY)%" "%UPSTREAM_HOST%"
+//
+// as the first run of five consecutive quoted fields
+var (
+	textRequestPattern = regexp.MustCompile(`^\[([^\]]+)\] "(\S+) (\S+) ([^"]*)" (\d+|-)`)
+	textHeaderPattern  = regexp.MustCompile(`"([^"]*)" "([^"]*)" "([^"]*)" "([^"]*)" "([^"]*)"`)
+)
+
+// ParseLine parses an access log line in either JSON or the default text format
+func ParseLine(line string) (Request, error) {
+	if strings.HasPrefix(line, "{") {
+		return parseJSON(line)
+	}
+	return parseText(line)
+}
+
+func parseJSON(line string) (Request, error) {
+	var req Request
+	if err := json.Unmarshal([]byte(line), &req); err != nil {
+		return req, err
+	}
+
+	req.normalize()
+	return req, nil
+}
+
+func parseText(line string) (Request, error) {
+	var req Request
+
+	match := textRequestPattern.FindStringSubmatch(line)
+	if match == nil {
+		return req, fmt.Errorf("line does not match the default access log format")
+	}
+
+	req.StartTime, req.Method, req.Path, req.Protocol = match[1], match[2], match[3], match[4]
+
+	if match[5] != "-" {
+		code, err := strconv.Atoi(match[5])
+		if err != nil {
+			return req, fmt.Errorf("invalid response code %s: %w", match[5], err)
+		}
+		req.ResponseCode = code
+	}
+
+	headers := textHeaderPattern.FindStringSubmatch(line[len(match[0]):])
+	if headers == nil {
+		return req, fmt.Errorf("line does not contain the request headers of the default access log format")
+	}
+
+	req.XForwardedFor, req.UserAgent, req.RequestID, req.Authority, req.UpstreamHost = headers[1], headers[2], headers[3], headers[4], headers[5]
+
+	req.normalize()
+	return req, nil
+}
+
+// normalize clears fields Envoy logged as "-" because they were not set
+func (r *Request) normalize() {
+	for _, field := range []*string{&r.Method, &r.Path, &r.Protocol, &r.XForwardedFor, &r.UserAgent, &r.RequestID, &r.Authority, &r.UpstreamHost} {
+		if *field == "-" {
+			*field = ""
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package envoy
+
+import (
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected Request
+	}{
+		{
+			name: "envoy default format",
+			line: `[2016-04-15T20:17:00.310Z] "POST /api/v1/locations HTTP/2" 204 - 154 0 226 100 "10.0.35.28" "nsq2http" "cc21d9b0-cf5c-432b-8c7e-98aeb7988cd2" "locations" "tcp://10.0.2.1:80"`,
+			expected: Request{
+				StartTime:     "2016-04-15T20:17:00.310Z",
+				Method:        "POST",
+				Path:          "/api/v1/locations",
+				Protocol:      "HTTP/2",
+				ResponseCode:  204,
+				XForwardedFor: "10.0.35.28",
+				UserAgent:     "nsq2http",
+				RequestID:     "cc21d9b0-cf5c-432b-8c7e-98aeb7988cd2",
+				Authority:     "locations",
+				UpstreamHost:  "tcp://10.0.2.1:80",
+			},
+		},
+		{
+			name: "istio default format",
+			line: `[2025-09-03T15:30:33.123Z] "GET /status/418 HTTP/1.1" 418 - via_upstream - "-" 0 135 4 4 "-" "curl/8.0" "d4e5f6a7" "httpbin:8000" "10.0.2.2:80" outbound|8000||httpbin.foo.svc.cluster.local 10.0.1.5:43210 10.0.2.2:80 10.0.1.5:56789 - default`,
+			expected: Request{
+				StartTime:    "2025-09-03T15:30:33.123Z",
+				Method:       "GET",
+				Path:         "/status/418",
+				Protocol:     "HTTP/1.1",
+				ResponseCode: 418,
+				UserAgent:    "curl/8.0",
+				RequestID:    "d4e5f6a7",
+				Authority:    "httpbin:8000",
+				UpstreamHost: "10.0.2.2:80",
+			},
+		},
+		{
+			name: "istio JSON format",
+			line: `{"start_time":"2025-09-03T15:30:34.500Z","method":"DELETE","path":"/items/7","protocol":"HTTP/1.1","response_code":204,"duration":12,"x_forwarded_for":null,"user_agent":"-","request_id":"e1","authority":"api.test.com","upstream_host":"10.0.2.3:8080","bytes_sent":0}`,
+			expected: Request{
+				StartTime:    "2025-09-03T15:30:34.500Z",
+				Method:       "DELETE",
+				Path:         "/items/7",
+				Protocol:     "HTTP/1.1",
+				ResponseCode: 204,
+				Duration:     12,
+				RequestID:    "e1",
+				Authority:    "api.test.com",
+				UpstreamHost: "10.0.2.3:8080",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := ParseLine(tt.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if req != tt.expected {
+				t.Errorf("got %+v, want %+v", req, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseLineInvalid(t *testing.T) {
+	lines := []string{
+		`not an access log line`,
+		`[2016-04-15T20:17:00.310Z] "POST /api HTTP/2" 204 - 154 0 226 100`,
+		`{"start_time": 1}`,
+	}
+
+	for _, line := range lines {
+		if _, err := ParseLine(line); err == nil {
+			t.Errorf("expected error for line %s but got none", line)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/loveholidays/ripley/pkg/convert"
+	"github.com/loveholidays/ripley/tools/harxripley/pkg/converter"
+)
+
+func main() {
+	var options convert.Options
+	options.RegisterFlags(flag.CommandLine)
+	help := flag.Bool("help", false, "Show usage information")
+	flag.Parse()
+
+	if *help {
+		fmt.Fprintf(os.Stderr, "harxripley - Convert HAR 1.2 files to Ripley format\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: harxripley [options] < input.har > output.jsonl\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  cat session.har | harxripley -host localhost:8080 > ripley.jsonl\n")
+		fmt.Fprintf(os.Stderr, "  cat session.har | harxripley -host localhost:8443 -https > ripley.jsonl\n\n")
+		return
+	}
+
+	stats, err := convert.Run(os.Stdin, os.Stdout, converter.New(), options)
+
+	if reportErr := stats.Report(os.Stderr); reportErr != nil {
+		log.Printf("failed to write report: %v", reportErr)
+	}
+
+	if err != nil {
+		log.Fatalf("error converting input: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCLIIntegration(t *testing.T) {
+	input, err := os.ReadFile("testdata/sample.har")
+	if err != nil {
+		t.Fatalf("failed to read sample HAR: %v", err)
+	}
+
+	output := runCLICommand(t, []string{"-host", "localhost:8080"}, string(input))
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 output lines, got %d", len(lines))
+	}
+
+	// Entries are sorted by startedDateTime
+	first := parseJSONOutput(t, lines[0])
+	assertFieldEquals(t, first, "method", "GET")
+	assertFieldEquals(t, first, "url", "https://localhost:8080/api/v1/data?id=12345")
+	assertFieldEquals(t, first, "timestamp", "2025-09-03T15:30:32.928Z")
+
+	second := parseJSONOutput(t, lines[1])
+	assertFieldEquals(t, second, "method", "POST")
+	assertFieldEquals(t, second, "url", "http://localhost:8080/api/v1/search")
+	assertFieldEquals(t, second, "body", `{"q": "hotel"}`)
+
+	headers, ok := second["headers"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("headers is not an object: %v", second["headers"])
+	}
+
+	cookies, ok := headers["Cookie"].([]interface{})
+	if !ok || len(cookies) != 2 {
+		t.Errorf("expected 2 Cookie headers, got %v", headers["Cookie"])
+	}
+}
+
+func TestCLIInvalidInput(t *testing.T) {
+	cmd := exec.Command("go", "run", "main.go")
+	cmd.Stdin = strings.NewReader("not a HAR file")
+
+	if err := cmd.Run(); err == nil {
+		t.Errorf("expected invalid input to fail")
+	}
+}
+
+func runCLICommand(t *testing.T, args []string, input string) string {
+	cmdArgs := append([]string{"run", "main.go"}, args...)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Dir = "."
+	cmd.Stdin = strings.NewReader(input)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nstderr: %s", err, stderr.String())
+	}
+
+	return stdout.String()
+}
+
+func parseJSONOutput(t *testing.T, output string) map[string]interface{} {
+	var result map[string]interface{}
+	err := json.Unmarshal([]byte(strings.TrimSpace(output)), &result)
+	if err != nil {
+		t.Fatalf("failed to parse output JSON: %v", err)
+	}
+	return result
+}
+
+func assertFieldEquals(t *testing.T, result map[string]interface{}, field, expected string) {
+	if result[field] != expected {
+		t.Errorf("expected %s %s, got %v", field, expected, result[field])
+	}
+}
@@ -0,0 +1,196 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
+	"github.com/loveholidays/ripley/tools/harxripley/pkg/har"
+)
+
+type Converter struct{}
+
+var (
+	_ convert.Converter    = (*Converter)(nil)
+	_ convert.RecordReader = (*Converter)(nil)
+)
+
+func New() *Converter {
+	return &Converter{}
+}
+
+// Records yields the JSON of each HAR entry, sorted by startedDateTime as
+// ripley expects requests in chronological order, which HAR does not guarantee
+func (c *Converter) Records(r io.Reader) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		var archive struct {
+			Log struct {
+				Entries []json.RawMessage `json:"entries"`
+			} `json:"log"`
+		}
+
+		if err := json.NewDecoder(r).Decode(&archive); err != nil {
+			yield(nil, fmt.Errorf("failed to parse HAR: %w", err))
+			return
+		}
+
+		type startedEntry struct {
+			start time.Time
+			raw   json.RawMessage
+		}
+
+		entries := make([]startedEntry, len(archive.Log.Entries))
+
+		for i, raw := range archive.Log.Entries {
+			entries[i].raw = raw
+
+			var entry struct {
+				StartedDateTime string `json:"startedDateTime"`
+			}
+			// Invalid entries sort first and fail to convert
+			if json.Unmarshal(raw, &entry) == nil {
+				entries[i].start, _ = c.parseTimestamp(entry.StartedDateTime)
+			}
+		}
+
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].start.Before(entries[j].start)
+		})
+
+		for _, entry := range entries {
+			if !yield(entry.raw, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Convert converts the JSON of a single HAR entry
+func (c *Converter) Convert(record []byte, options convert.Options) (*ripley.Request, error) {
+	var entry har.Entry
+	if err := json.Unmarshal(record, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse entry: %w", err)
+	}
+
+	return c.convertEntry(entry, options)
+}
+
+func (c *Converter) ConvertToRipley(entry har.Entry, newHost string, upgradeHTTPS bool) (*ripley.Request, error) {
+	return c.convertEntry(entry, convert.Options{Host: newHost, HTTPS: upgradeHTTPS})
+}
+
+func (c *Converter) convertEntry(entry har.Entry, options convert.Options) (*ripley.Request, error) {
+	timestamp, err := c.parseTimestamp(entry.StartedDateTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse startedDateTime %s: %w", entry.StartedDateTime, err)
+	}
+
+	originalURL, err := c.buildTargetURL(entry.Request, convert.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build target URL: %w", err)
+	}
+
+	targetURL, err := c.buildTargetURL(entry.Request, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build target URL: %w", err)
+	}
+
+	ripleyReq := &ripley.Request{
+		Method:    entry.Request.Method,
+		Url:       targetURL,
+		Timestamp: timestamp,
+		Headers:   c.buildHeaders(entry.Request, hostOf(targetURL) != hostOf(originalURL)),
+	}
+
+	c.setBody(ripleyReq, entry.Request.PostData)
+
+	return ripleyReq, nil
+}
+
+func (c *Converter) parseTimestamp(timestampStr string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, timestampStr)
+}
+
+func (c *Converter) buildTargetURL(req har.Request, options convert.Options) (string, error) {
+	parsedURL, err := url.Parse(req.URL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL %s: %w", req.URL, err)
+	}
+
+	// The URL normally includes the query string already
+	if parsedURL.RawQuery == "" && len(req.QueryString) > 0 {
+		query := url.Values{}
+		for _, param := range req.QueryString {
+			query.Add(param.Name, param.Value)
+		}
+		parsedURL.RawQuery = query.Encode()
+	}
+
+	return options.RewriteURL(parsedURL.String())
+}
+
+func hostOf(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsedURL.Host
+}
+
+func (c *Converter) buildHeaders(req har.Request, hostChanged bool) ripley.Headers {
+	headers := make(ripley.Headers)
+
+	for _, header := range req.Headers {
+		// HTTP/2 pseudo headers are derived from the URL and method
+		if strings.HasPrefix(header.Name, ":") {
+			continue
+		}
+
+		name := http.CanonicalHeaderKey(header.Name)
+
+		// Recomputed from the body when the request is sent
+		if name == "Content-Length" {
+			continue
+		}
+
+		// Would route the request to the original host
+		if name == "Host" && hostChanged {
+			continue
+		}
+
+		headers[name] = append(headers[name], header.Value)
+	}
+
+	if req.PostData != nil && req.PostData.MimeType != "" && len(headers["Content-Type"]) == 0 {
+		headers["Content-Type"] = []string{req.PostData.MimeType}
+	}
+
+	return headers
+}
+
+func (c *Converter) setBody(ripleyReq *ripley.Request, postData *har.PostData) {
+	if postData == nil {
+		return
+	}
+
+	switch {
+	case postData.Encoding == "base64":
+		ripleyReq.BodyBase64 = postData.Text
+	case postData.Text != "":
+		ripleyReq.Body = postData.Text
+	case len(postData.Params) > 0:
+		form := url.Values{}
+		for _, param := range postData.Params {
+			form.Add(param.Name, param.Value)
+		}
+		ripleyReq.Body = form.Encode()
+	}
+}
@@ -0,0 +1,312 @@
+package converter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
+	"github.com/loveholidays/ripley/tools/harxripley/pkg/har"
+)
+
+func TestConverter_ConvertToRipley(t *testing.T) {
+	conv := New()
+
+	tests := []struct {
+		name         string
+		entry        har.Entry
+		newHost      string
+		upgradeHTTPS bool
+		expected     *ripley.Request
+	}{
+		{
+			name:  "GET without host change",
+			entry: createEntry("GET", "http://api.test.com/api/v1/data?id=12345", nil),
+			expected: &ripley.Request{
+				Method:    "GET",
+				Url:       "http://api.test.com/api/v1/data?id=12345",
+				Timestamp: mustParseTime("2025-09-03T15:30:32.928+01:00"),
+				Headers: ripley.Headers{
+					"Host":       {"api.test.com"},
+					"User-Agent": {"TestBrowser/1.0"},
+					"Cookie":     {"a=1", "b=2"},
+				},
+			},
+		},
+		{
+			name:         "host change and HTTPS upgrade drop the original Host header",
+			entry:        createEntry("GET", "http://api.test.com/api/v1/data", nil),
+			newHost:      "localhost:8443",
+			upgradeHTTPS: true,
+			expected: &ripley.Request{
+				Method:    "GET",
+				Url:       "https://localhost:8443/api/v1/data",
+				Timestamp: mustParseTime("2025-09-03T15:30:32.928+01:00"),
+				Headers: ripley.Headers{
+					"User-Agent": {"TestBrowser/1.0"},
+					"Cookie":     {"a=1", "b=2"},
+				},
+			},
+		},
+		{
+			name:    "POST with text body",
+			entry:   createEntry("POST", "http://api.test.com/search", &har.PostData{MimeType: "application/json", Text: `{"q":"hotel"}`}),
+			newHost: "localhost:8080",
+			expected: &ripley.Request{
+				Method:    "POST",
+				Url:       "http://localhost:8080/search",
+				Body:      `{"q":"hotel"}`,
+				Timestamp: mustParseTime("2025-09-03T15:30:32.928+01:00"),
+				Headers: ripley.Headers{
+					"User-Agent":   {"TestBrowser/1.0"},
+					"Cookie":       {"a=1", "b=2"},
+					"Content-Type": {"application/json"},
+				},
+			},
+		},
+		{
+			name:    "POST with form params",
+			entry:   createEntry("POST", "http://api.test.com/login", &har.PostData{MimeType: "application/x-www-form-urlencoded", Params: []har.NameValue{{Name: "user", Value: "a b"}, {Name: "remember", Value: "1"}}}),
+			newHost: "localhost:8080",
+			expected: &ripley.Request{
+				Method:    "POST",
+				Url:       "http://localhost:8080/login",
+				Body:      "remember=1&user=a+b",
+				Timestamp: mustParseTime("2025-09-03T15:30:32.928+01:00"),
+				Headers: ripley.Headers{
+					"User-Agent":   {"TestBrowser/1.0"},
+					"Cookie":       {"a=1", "b=2"},
+					"Content-Type": {"application/x-www-form-urlencoded"},
+				},
+			},
+		},
+		{
+			name:    "POST with base64 encoded body",
+			entry:   createEntry("POST", "http://api.test.com/upload", &har.PostData{MimeType: "application/x-protobuf", Text: "AAH/", Encoding: "base64"}),
+			newHost: "localhost:8080",
+			expected: &ripley.Request{
+				Method:     "POST",
+				Url:        "http://localhost:8080/upload",
+				BodyBase64: "AAH/",
+				Timestamp:  mustParseTime("2025-09-03T15:30:32.928+01:00"),
+				Headers: ripley.Headers{
+					"User-Agent":   {"TestBrowser/1.0"},
+					"Cookie":       {"a=1", "b=2"},
+					"Content-Type": {"application/x-protobuf"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := conv.ConvertToRipley(tt.entry, tt.newHost, tt.upgradeHTTPS)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertConversionResult(t, result, tt.expected)
+		})
+	}
+}
+
+func TestConverter_ConvertToRipleyErrors(t *testing.T) {
+	conv := New()
+
+	invalidTimestamp := createEntry("GET", "http://api.test.com/", nil)
+	invalidTimestamp.StartedDateTime = "invalid-timestamp"
+
+	invalidURL := createEntry("GET", "://invalid-url", nil)
+
+	for _, entry := range []har.Entry{invalidTimestamp, invalidURL} {
+		if _, err := conv.ConvertToRipley(entry, "localhost:8080", false); err == nil {
+			t.Errorf("expected error for %+v but got none", entry)
+		}
+	}
+}
+
+func TestConverter_buildTargetURL(t *testing.T) {
+	conv := New()
+
+	tests := []struct {
+		name     string
+		request  har.Request
+		expected string
+	}{
+		{
+			name:     "query in URL",
+			request:  har.Request{URL: "http://api.test.com/search?q=a", QueryString: []har.NameValue{{Name: "q", Value: "a"}}},
+			expected: "http://api.test.com/search?q=a",
+		},
+		{
+			name:     "query only in queryString",
+			request:  har.Request{URL: "http://api.test.com/search", QueryString: []har.NameValue{{Name: "q", Value: "a b"}, {Name: "page", Value: "2"}}},
+			expected: "http://api.test.com/search?page=2&q=a+b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := conv.buildTargetURL(tt.request, convert.Options{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("got %s, want %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConverter_buildHeadersSkipsDerivedHeaders(t *testing.T) {
+	conv := New()
+
+	request := har.Request{
+		Headers: []har.NameValue{
+			{Name: ":authority", Value: "api.test.com"},
+			{Name: ":path", Value: "/"},
+			{Name: "content-length", Value: "10"},
+			{Name: "accept", Value: "text/html"},
+		},
+	}
+
+	headers := conv.buildHeaders(request, false)
+
+	if len(headers) != 1 || !slices.Equal(headers["Accept"], []string{"text/html"}) {
+		t.Errorf("got %v, want map[Accept:[text/html]]", headers)
+	}
+}
+
+func createEntry(method, url string, postData *har.PostData) har.Entry {
+	return har.Entry{
+		StartedDateTime: "2025-09-03T15:30:32.928+01:00",
+		Request: har.Request{
+			Method:      method,
+			URL:         url,
+			HTTPVersion: "HTTP/1.1",
+			Headers: []har.NameValue{
+				{Name: "Host", Value: "api.test.com"},
+				{Name: "User-Agent", Value: "TestBrowser/1.0"},
+				{Name: "Cookie", Value: "a=1"},
+				{Name: "Cookie", Value: "b=2"},
+			},
+			PostData: postData,
+		},
+	}
+}
+
+func assertConversionResult(t *testing.T, result, expected *ripley.Request) {
+	if result.Method != expected.Method {
+		t.Errorf("Method: got %s, want %s", result.Method, expected.Method)
+	}
+
+	if result.Url != expected.Url {
+		t.Errorf("URL: got %s, want %s", result.Url, expected.Url)
+	}
+
+	if result.Body != expected.Body {
+		t.Errorf("Body: got %s, want %s", result.Body, expected.Body)
+	}
+
+	if result.BodyBase64 != expected.BodyBase64 {
+		t.Errorf("BodyBase64: got %s, want %s", result.BodyBase64, expected.BodyBase64)
+	}
+
+	if !result.Timestamp.Equal(expected.Timestamp) {
+		t.Errorf("Timestamp: got %v, want %v", result.Timestamp, expected.Timestamp)
+	}
+
+	if len(result.Headers) != len(expected.Headers) {
+		t.Errorf("Headers: got %v, want %v", result.Headers, expected.Headers)
+		return
+	}
+
+	for k, v := range expected.Headers {
+		if !slices.Equal(result.Headers[k], v) {
+			t.Errorf("Header %s: got %v, want %v", k, result.Headers[k], v)
+		}
+	}
+}
+
+func mustParseTime(timeStr string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, timeStr)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestConverter_RecordsAndConvert(t *testing.T) {
+	conv := New()
+	input := `{"log":{"entries":[
+		{"startedDateTime":"2025-09-03T15:30:33.000Z","request":{"method":"POST","url":"http://api.example.com/second","headers":[{"name":"Host","value":"api.example.com"}]}},
+		{"startedDateTime":"2025-09-03T15:30:32.000Z","request":{"method":"GET","url":"http://api.example.com/first","headers":[{"name":"Host","value":"api.example.com"}]}}
+	]}}`
+	options := convert.Options{HostMap: map[string]string{"api.example.com": "localhost:8080"}}
+
+	var urls []string
+	for record, err := range conv.Records(strings.NewReader(input)) {
+		if err != nil {
+			t.Fatalf("Records() error = %v", err)
+		}
+
+		result, err := conv.Convert(record, options)
+		if err != nil {
+			t.Fatalf("Convert() error = %v", err)
+		}
+
+		if _, ok := result.Headers["Host"]; ok {
+			t.Errorf("Host header = %v; want none once the host is mapped", result.Headers["Host"])
+		}
+
+		urls = append(urls, result.Url)
+	}
+
+	expected := []string{"http://localhost:8080/first", "http://localhost:8080/second"}
+	if !slices.Equal(urls, expected) {
+		t.Errorf("urls = %v; want %v", urls, expected)
+	}
+
+	for _, err := range conv.Records(strings.NewReader("not a HAR file")) {
+		if err == nil {
+			t.Errorf("Records() of invalid input error = nil; want error")
+		}
+	}
+}
+
+func TestConverter_RecordsSortsShuffledEntries(t *testing.T) {
+	conv := New()
+
+	order := []int{3, 1, 4, 0, 5, 2, 9, 7, 8, 6}
+	entries := make([]string, len(order))
+	for i, n := range order {
+		entries[i] = fmt.Sprintf(`{"startedDateTime":"2025-09-03T15:30:%02d.000Z","request":{"method":"GET","url":"http://api.example.com/%d"}}`, n, n)
+	}
+	input := `{"log":{"entries":[` + strings.Join(entries, ",") + `]}}`
+
+	var urls []string
+	for record, err := range conv.Records(strings.NewReader(input)) {
+		if err != nil {
+			t.Fatalf("Records() error = %v", err)
+		}
+
+		result, err := conv.Convert(record, convert.Options{})
+		if err != nil {
+			t.Fatalf("Convert() error = %v", err)
+		}
+
+		urls = append(urls, result.Url)
+	}
+
+	var expected []string
+	for n := 0; n < len(order); n++ {
+		expected = append(expected, fmt.Sprintf("http://api.example.com/%d", n))
+	}
+
+	if !slices.Equal(urls, expected) {
+		t.Errorf("urls = %v; want %v", urls, expected)
+	}
+}
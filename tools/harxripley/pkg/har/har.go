@@ -0,0 +1,41 @@
+package har
+
+// HAR 1.2 as specified in http://www.softwareishard.com/blog/har-12-spec/,
+// limited to the fields needed to replay requests
+
+type HAR struct {
+	Log Log `json:"log"`
+}
+
+type Log struct {
+	Version string  `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+type Entry struct {
+	StartedDateTime string  `json:"startedDateTime"`
+	Time            float64 `json:"time"`
+	Request         Request `json:"request"`
+}
+
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+}
+
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type PostData struct {
+	MimeType string      `json:"mimeType"`
+	Params   []NameValue `json:"params"`
+	Text     string      `json:"text"`
+	// Not part of HAR 1.2, but written by some tools for binary payloads
+	Encoding string `json:"encoding,omitempty"`
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/loveholidays/ripley/pkg/convert"
+	"github.com/loveholidays/ripley/tools/lbxripley/pkg/alb"
+	"github.com/loveholidays/ripley/tools/lbxripley/pkg/cloudfront"
+	"github.com/loveholidays/ripley/tools/lbxripley/pkg/gcp"
+)
+
+func main() {
+	var options convert.Options
+	options.RegisterFlags(flag.CommandLine)
+	format := flag.String("format", "", `Load balancer log format, "alb", "cloudfront" or "gcp"`)
+	help := flag.Bool("help", false, "Show usage information")
+	flag.Parse()
+
+	if *help {
+		fmt.Fprintf(os.Stderr, "lbxripley - Convert cloud load balancer logs to Ripley format\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: lbxripley -format alb|cloudfront|gcp [options] < input.log > output.jsonl\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  zcat alb-logs/*.log.gz | lbxripley -format alb -host localhost:8080 > ripley.jsonl\n")
+		fmt.Fprintf(os.Stderr, "  zcat cloudfront-logs/*.gz | lbxripley -format cloudfront -host localhost:8443 -https > ripley.jsonl\n\n")
+		return
+	}
+
+	var conv convert.Converter
+	switch *format {
+	case "alb":
+		conv = alb.New()
+	case "cloudfront":
+		conv = cloudfront.New()
+	case "gcp":
+		conv = gcp.New()
+	default:
+		log.Fatalf("unknown format: %q, use alb, cloudfront or gcp", *format)
+	}
+
+	stats, err := convert.Run(os.Stdin, os.Stdout, conv, options)
+
+	if reportErr := stats.Report(os.Stderr); reportErr != nil {
+		log.Printf("failed to write report: %v", reportErr)
+	}
+
+	if err != nil {
+		log.Fatalf("error converting input: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCLIIntegration(t *testing.T) {
+	tests := []struct {
+		format   string
+		file     string
+		expected []string
+	}{
+		{"alb", "testdata/alb.log", []string{"http://localhost:8080/api/v1/data?id=12345", "https://localhost:8080/api/v1/create"}},
+		{"cloudfront", "testdata/cloudfront.log", []string{"https://localhost:8080/api/v1/data?id=12345", "https://localhost:8080/api/v1/create"}},
+		{"gcp", "testdata/gcp.jsonl", []string{"https://localhost:8080/api/v1/data?id=12345", "https://localhost:8080/api/v1/create"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			input, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", tt.file, err)
+			}
+
+			output := runCLICommand(t, []string{"-format", tt.format, "-host", "localhost:8080"}, string(input))
+
+			lines := strings.Split(strings.TrimSpace(output), "\n")
+			if len(lines) != len(tt.expected) {
+				t.Fatalf("expected %d output lines, got %d", len(tt.expected), len(lines))
+			}
+
+			for i, line := range lines {
+				assertFieldEquals(t, parseJSONOutput(t, line), "url", tt.expected[i])
+			}
+		})
+	}
+}
+
+func TestCLIUnknownFormat(t *testing.T) {
+	cmd := exec.Command("go", "run", "main.go", "-format", "iis")
+	cmd.Stdin = strings.NewReader("")
+
+	if err := cmd.Run(); err == nil {
+		t.Errorf("expected unknown format to fail")
+	}
+}
+
+func runCLICommand(t *testing.T, args []string, input string) string {
+	cmdArgs := append([]string{"run", "main.go"}, args...)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Dir = "."
+	cmd.Stdin = strings.NewReader(input)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nstderr: %s", err, stderr.String())
+	}
+
+	return stdout.String()
+}
+
+func parseJSONOutput(t *testing.T, output string) map[string]interface{} {
+	var result map[string]interface{}
+	err := json.Unmarshal([]byte(strings.TrimSpace(output)), &result)
+	if err != nil {
+		t.Fatalf("failed to parse output JSON: %v", err)
+	}
+	return result
+}
+
+func assertFieldEquals(t *testing.T, result map[string]interface{}, field, expected string) {
+	if result[field] != expected {
+		t.Errorf("expected %s %s, got %v", field, expected, result[field])
+	}
+}
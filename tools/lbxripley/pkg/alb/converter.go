@@ -0,0 +1,160 @@
+package alb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
+)
+
+// Positions of the fields used from an ALB access log entry, see
+// https://docs.aws.amazon.com/elasticloadbalancing/latest/application/load-balancer-access-logs.html
+const (
+	fieldTime                = 1
+	fieldTargetTime          = 6
+	fieldStatus              = 8
+	fieldRequest             = 12
+	fieldUserAgent           = 13
+	fieldRequestCreationTime = 21
+	minFields                = 14
+)
+
+// Converter converts AWS Application Load Balancer access log entries
+type Converter struct{}
+
+var _ convert.Converter = (*Converter)(nil)
+
+func New() *Converter {
+	return &Converter{}
+}
+
+func (c *Converter) Convert(line []byte, options convert.Options) (*ripley.Request, error) {
+	fields, err := splitFields(string(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) < minFields {
+		return nil, fmt.Errorf("expected at least %d fields, got %d", minFields, len(fields))
+	}
+
+	timestamp, err := c.parseTimestamp(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// e.g. "GET https://www.example.com:443/path?query HTTP/2.0"
+	request := strings.Fields(fields[fieldRequest])
+	if len(request) != 3 || request[1] == "-" {
+		return nil, fmt.Errorf("invalid request: %s", fields[fieldRequest])
+	}
+
+	targetURL, err := options.RewriteURL(request[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to build target URL: %w", err)
+	}
+
+	status, err := c.parseStatus(fields[fieldStatus])
+	if err != nil {
+		return nil, err
+	}
+
+	latency, err := c.parseLatency(fields[fieldTargetTime])
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(ripley.Headers)
+	if userAgent := fields[fieldUserAgent]; userAgent != "-" && userAgent != "" {
+		headers["User-Agent"] = []string{userAgent}
+	}
+
+	return &ripley.Request{
+		Method:          request[0],
+		Url:             targetURL,
+		Timestamp:       timestamp,
+		Headers:         headers,
+		ExpectedStatus:  status,
+		OriginalLatency: latency,
+	}, nil
+}
+
+// parseStatus parses elb_status_code, which is "-" when the connection was
+// closed before a response was sent
+func (c *Converter) parseStatus(status string) (int, error) {
+	if status == "-" {
+		return 0, nil
+	}
+
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse elb_status_code %s: %w", status, err)
+	}
+
+	return code, nil
+}
+
+// parseLatency parses target_processing_time in seconds, which is -1 when
+// the target did not respond
+func (c *Converter) parseLatency(latency string) (time.Duration, error) {
+	if latency == "-1" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(latency + "s")
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("failed to parse target_processing_time %s", latency)
+	}
+
+	return duration, nil
+}
+
+// parseTimestamp prefers the time the request was received over the time the
+// response was sent, which is all older log entries have
+func (c *Converter) parseTimestamp(fields []string) (time.Time, error) {
+	timestampStr := fields[fieldTime]
+	if len(fields) > fieldRequestCreationTime && fields[fieldRequestCreationTime] != "-" {
+		timestampStr = fields[fieldRequestCreationTime]
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, timestampStr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse timestamp %s: %w", timestampStr, err)
+	}
+
+	return timestamp, nil
+}
+
+// splitFields splits a line on spaces, keeping double quoted fields together
+func splitFields(line string) ([]string, error) {
+	var fields []string
+
+	for line != "" {
+		if line[0] == ' ' {
+			line = line[1:]
+			continue
+		}
+
+		if line[0] == '"' {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted field")
+			}
+			fields = append(fields, line[1:end+1])
+			line = line[end+2:]
+			continue
+		}
+
+		end := strings.IndexByte(line, ' ')
+		if end < 0 {
+			end = len(line)
+		}
+		fields = append(fields, line[:end])
+		line = line[end:]
+	}
+
+	return fields, nil
+}
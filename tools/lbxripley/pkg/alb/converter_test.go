@@ -0,0 +1,156 @@
+package alb
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
+)
+
+const sampleLine = `h2 2025-09-03T15:30:34.086641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 201 201 34 366 "POST https://www.test.com:443/api/v1/create?x=1 HTTP/2.0" "Mozilla/5.0 (Macintosh)" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe355" "www.test.com" "-" 1 2025-09-03T15:30:33.123000Z "forward" "-" "-" "10.0.0.1:80" "201" "-" "-"`
+
+func TestConverter_Convert(t *testing.T) {
+	conv := New()
+
+	tests := []struct {
+		name     string
+		line     string
+		options  convert.Options
+		expected *ripley.Request
+	}{
+		{
+			name: "request creation time and full URL",
+			line: sampleLine,
+			expected: &ripley.Request{
+				Method:          "POST",
+				Url:             "https://www.test.com:443/api/v1/create?x=1",
+				Timestamp:       time.Date(2025, 9, 3, 15, 30, 33, 123000000, time.UTC),
+				Headers:         ripley.Headers{"User-Agent": {"Mozilla/5.0 (Macintosh)"}},
+				ExpectedStatus:  201,
+				OriginalLatency: time.Millisecond,
+			},
+		},
+		{
+			name:    "host change",
+			line:    sampleLine,
+			options: convert.Options{Host: "localhost:8443"},
+			expected: &ripley.Request{
+				Method:          "POST",
+				Url:             "https://localhost:8443/api/v1/create?x=1",
+				Timestamp:       time.Date(2025, 9, 3, 15, 30, 33, 123000000, time.UTC),
+				Headers:         ripley.Headers{"User-Agent": {"Mozilla/5.0 (Macintosh)"}},
+				ExpectedStatus:  201,
+				OriginalLatency: time.Millisecond,
+			},
+		},
+		{
+			name:    "older entry without request creation time and HTTPS upgrade",
+			line:    `http 2025-09-03T15:30:33.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.test.com:80/ HTTP/1.1" "-" - -`,
+			options: convert.Options{HTTPS: true},
+			expected: &ripley.Request{
+				Method:          "GET",
+				Url:             "https://www.test.com:80/",
+				Timestamp:       time.Date(2025, 9, 3, 15, 30, 33, 186641000, time.UTC),
+				Headers:         ripley.Headers{},
+				ExpectedStatus:  200,
+				OriginalLatency: time.Millisecond,
+			},
+		},
+		{
+			name: "target did not respond",
+			line: `http 2025-09-03T15:30:33.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 -1 -1 504 - 34 366 "GET http://www.test.com:80/slow HTTP/1.1" "-" - -`,
+			expected: &ripley.Request{
+				Method:         "GET",
+				Url:            "http://www.test.com:80/slow",
+				Timestamp:      time.Date(2025, 9, 3, 15, 30, 33, 186641000, time.UTC),
+				Headers:        ripley.Headers{},
+				ExpectedStatus: 504,
+			},
+		},
+		{
+			name: "connection closed before a response",
+			line: `http 2025-09-03T15:30:33.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 - -1 -1 -1 - - 34 0 "GET http://www.test.com:80/ HTTP/1.1" "-" - -`,
+			expected: &ripley.Request{
+				Method:    "GET",
+				Url:       "http://www.test.com:80/",
+				Timestamp: time.Date(2025, 9, 3, 15, 30, 33, 186641000, time.UTC),
+				Headers:   ripley.Headers{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := conv.Convert([]byte(tt.line), tt.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertConversionResult(t, result, tt.expected)
+		})
+	}
+}
+
+func TestConverter_ConvertErrors(t *testing.T) {
+	conv := New()
+
+	lines := []string{
+		`http 2025-09-03T15:30:33.186641Z app/my-loadbalancer/50dc6c495c0c9188`,
+		`http 2025-09-03T15:30:33.186641Z app/lb 1:2 3:4 0 0 0 460 - 34 0 "- - - " "-" - -`,
+		`http yesterday app/lb 1:2 3:4 0 0 0 200 200 34 0 "GET http://a/ HTTP/1.1" "-" - -`,
+		`http 2025-09-03T15:30:33.186641Z app/lb 1:2 3:4 0 0 0 200 200 34 0 "GET http://a/ HTTP/1.1`,
+		`http 2025-09-03T15:30:33.186641Z app/lb 1:2 3:4 0 0 0 OK 200 34 0 "GET http://a/ HTTP/1.1" "-" - -`,
+		`http 2025-09-03T15:30:33.186641Z app/lb 1:2 3:4 0 fast 0 200 200 34 0 "GET http://a/ HTTP/1.1" "-" - -`,
+	}
+
+	for _, line := range lines {
+		if _, err := conv.Convert([]byte(line), convert.Options{}); err == nil {
+			t.Errorf("expected error for line %s but got none", line)
+		}
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	fields, err := splitFields(`a "b c" "" d`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := []string{"a", "b c", "", "d"}; !slices.Equal(fields, want) {
+		t.Errorf("got %q, want %q", fields, want)
+	}
+}
+
+func assertConversionResult(t *testing.T, result, expected *ripley.Request) {
+	if result.Method != expected.Method {
+		t.Errorf("Method: got %s, want %s", result.Method, expected.Method)
+	}
+
+	if result.Url != expected.Url {
+		t.Errorf("URL: got %s, want %s", result.Url, expected.Url)
+	}
+
+	if !result.Timestamp.Equal(expected.Timestamp) {
+		t.Errorf("Timestamp: got %v, want %v", result.Timestamp, expected.Timestamp)
+	}
+
+	if result.ExpectedStatus != expected.ExpectedStatus {
+		t.Errorf("ExpectedStatus: got %d, want %d", result.ExpectedStatus, expected.ExpectedStatus)
+	}
+
+	if result.OriginalLatency != expected.OriginalLatency {
+		t.Errorf("OriginalLatency: got %s, want %s", result.OriginalLatency, expected.OriginalLatency)
+	}
+
+	if len(result.Headers) != len(expected.Headers) {
+		t.Errorf("Headers: got %v, want %v", result.Headers, expected.Headers)
+		return
+	}
+
+	for k, v := range expected.Headers {
+		if !slices.Equal(result.Headers[k], v) {
+			t.Errorf("Header %s: got %v, want %v", k, result.Headers[k], v)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package cloudfront
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
+)
+
+// The fields of standard logs, unless a "#Fields:" header says otherwise, see
+// https://docs.aws.amazon.com/AmazonCloudFront/latest/DeveloperGuide/standard-logs-reference.html
+var defaultFields = []string{
+	"date", "time", "x-edge-location", "sc-bytes", "c-ip", "cs-method", "cs(Host)", "cs-uri-stem",
+	"sc-status", "cs(Referer)", "cs(User-Agent)", "cs-uri-query", "cs(Cookie)", "x-edge-result-type",
+	"x-edge-request-id", "x-host-header", "cs-protocol", "cs-bytes", "time-taken", "x-forwarded-for",
+	"ssl-protocol", "ssl-cipher", "x-edge-response-result-type", "cs-protocol-version", "fle-status",
+	"fle-encrypted-fields", "c-port", "time-to-first-byte", "x-edge-detailed-result-type",
+	"sc-content-type", "sc-content-len", "sc-range-start", "sc-range-end",
+}
+
+// Converter converts CloudFront standard log entries. It is not safe for
+// concurrent use, as it keeps track of the "#Fields:" header of the log.
+type Converter struct {
+	fields map[string]int
+}
+
+var _ convert.Converter = (*Converter)(nil)
+
+func New() *Converter {
+	c := &Converter{}
+	c.setFields(defaultFields)
+	return c
+}
+
+func (c *Converter) setFields(fields []string) {
+	c.fields = make(map[string]int, len(fields))
+	for i, field := range fields {
+		c.fields[field] = i
+	}
+}
+
+func (c *Converter) Convert(line []byte, options convert.Options) (*ripley.Request, error) {
+	text := string(line)
+
+	if strings.HasPrefix(text, "#") {
+		if fields, ok := strings.CutPrefix(text, "#Fields:"); ok {
+			c.setFields(strings.Fields(fields))
+		}
+		return nil, convert.ErrSkipLine
+	}
+
+	values := strings.Split(text, "\t")
+	field := func(name string) string {
+		if i, ok := c.fields[name]; ok && i < len(values) && values[i] != "-" {
+			return values[i]
+		}
+		return ""
+	}
+
+	timestampStr := field("date") + "T" + field("time") + "Z"
+	timestamp, err := time.Parse(time.RFC3339, timestampStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp %s: %w", timestampStr, err)
+	}
+
+	targetURL, err := c.buildTargetURL(field, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build target URL: %w", err)
+	}
+
+	method := field("cs-method")
+	if method == "" {
+		return nil, fmt.Errorf("missing cs-method")
+	}
+
+	var status int
+	if value := field("sc-status"); value != "" {
+		if status, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("failed to parse sc-status %s: %w", value, err)
+		}
+	}
+
+	// time-taken is in seconds, with millisecond resolution
+	var latency time.Duration
+	if value := field("time-taken"); value != "" {
+		if latency, err = time.ParseDuration(value + "s"); err != nil || latency < 0 {
+			return nil, fmt.Errorf("failed to parse time-taken %s", value)
+		}
+	}
+
+	headers := make(ripley.Headers)
+	for header, name := range map[string]string{"User-Agent": "cs(User-Agent)", "Referer": "cs(Referer)"} {
+		// CloudFront URL encodes header values
+		if value, err := url.PathUnescape(field(name)); err == nil && value != "" {
+			headers[header] = []string{value}
+		}
+	}
+
+	return &ripley.Request{
+		Method:          method,
+		Url:             targetURL,
+		Timestamp:       timestamp,
+		Headers:         headers,
+		ExpectedStatus:  status,
+		OriginalLatency: latency,
+	}, nil
+}
+
+func (c *Converter) buildTargetURL(field func(string) string, options convert.Options) (string, error) {
+	scheme := field("cs-protocol")
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	// The Host header the viewer sent, rather than the distribution's domain
+	host := field("x-host-header")
+	if host == "" {
+		host = field("cs(Host)")
+	}
+
+	targetURL := scheme + "://" + host + field("cs-uri-stem")
+	if query := field("cs-uri-query"); query != "" {
+		targetURL += "?" + query
+	}
+
+	return options.RewriteURL(targetURL)
+}
@@ -0,0 +1,111 @@
+package cloudfront
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
+)
+
+func TestConverter_ConvertDefaultFields(t *testing.T) {
+	conv := New()
+
+	line := strings.Join([]string{
+		"2025-09-03", "15:30:32", "LHR62-C2", "512", "192.0.2.100", "GET", "d111111abcdef8.cloudfront.net", "/api/v1/data",
+		"200", "https://www.test.com/", "Mozilla/5.0%20(X11;%20Linux%20x86_64)", "id=12345", "-", "Hit",
+		"SOX4xwn4XV6Q4rgb7XiVGOHms_BGlTAC4KyHmureZmBNrjGdRLiNIQ==", "www.test.com", "https", "23", "0.001",
+	}, "\t")
+
+	result, err := conv.Convert([]byte(line), convert.Options{Host: "localhost:8443"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertConversionResult(t, result, &ripley.Request{
+		Method:    "GET",
+		Url:       "https://localhost:8443/api/v1/data?id=12345",
+		Timestamp: time.Date(2025, 9, 3, 15, 30, 32, 0, time.UTC),
+		Headers: ripley.Headers{
+			"User-Agent": {"Mozilla/5.0 (X11; Linux x86_64)"},
+			"Referer":    {"https://www.test.com/"},
+		},
+		ExpectedStatus:  200,
+		OriginalLatency: time.Millisecond,
+	})
+}
+
+func TestConverter_ConvertFieldsHeader(t *testing.T) {
+	conv := New()
+
+	for _, header := range []string{"#Version: 1.0", "#Fields: time date cs-method cs(Host) cs-uri-stem cs-protocol"} {
+		if _, err := conv.Convert([]byte(header), convert.Options{}); !errors.Is(err, convert.ErrSkipLine) {
+			t.Errorf("Convert(%s) err = %v; want ErrSkipLine", header, err)
+		}
+	}
+
+	result, err := conv.Convert([]byte("15:30:32\t2025-09-03\tPOST\td111111abcdef8.cloudfront.net\t/create\thttp"), convert.Options{HTTPS: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertConversionResult(t, result, &ripley.Request{
+		Method:    "POST",
+		Url:       "https://d111111abcdef8.cloudfront.net/create",
+		Timestamp: time.Date(2025, 9, 3, 15, 30, 32, 0, time.UTC),
+		Headers:   ripley.Headers{},
+	})
+}
+
+func TestConverter_ConvertErrors(t *testing.T) {
+	conv := New()
+
+	lines := []string{
+		"not a log line",
+		"2025-09-03\t15:30:32\tLHR62-C2\t512\t192.0.2.100\t-\td111111abcdef8.cloudfront.net\t/",
+		"2025-09-03\t15:30:32\tLHR62-C2\t512\t192.0.2.100\tGET\td111111abcdef8.cloudfront.net\t/\tOK",
+		"2025-09-03\t15:30:32\tLHR62-C2\t512\t192.0.2.100\tGET\td111111abcdef8.cloudfront.net\t/\t200" + strings.Repeat("\t-", 9) + "\tslow",
+	}
+
+	for _, line := range lines {
+		if _, err := conv.Convert([]byte(line), convert.Options{}); err == nil {
+			t.Errorf("expected error for line %q but got none", line)
+		}
+	}
+}
+
+func assertConversionResult(t *testing.T, result, expected *ripley.Request) {
+	if result.Method != expected.Method {
+		t.Errorf("Method: got %s, want %s", result.Method, expected.Method)
+	}
+
+	if result.Url != expected.Url {
+		t.Errorf("URL: got %s, want %s", result.Url, expected.Url)
+	}
+
+	if !result.Timestamp.Equal(expected.Timestamp) {
+		t.Errorf("Timestamp: got %v, want %v", result.Timestamp, expected.Timestamp)
+	}
+
+	if result.ExpectedStatus != expected.ExpectedStatus {
+		t.Errorf("ExpectedStatus: got %d, want %d", result.ExpectedStatus, expected.ExpectedStatus)
+	}
+
+	if result.OriginalLatency != expected.OriginalLatency {
+		t.Errorf("OriginalLatency: got %s, want %s", result.OriginalLatency, expected.OriginalLatency)
+	}
+
+	if len(result.Headers) != len(expected.Headers) {
+		t.Errorf("Headers: got %v, want %v", result.Headers, expected.Headers)
+		return
+	}
+
+	for k, v := range expected.Headers {
+		if !slices.Equal(result.Headers[k], v) {
+			t.Errorf("Header %s: got %v, want %v", k, result.Headers[k], v)
+		}
+	}
+}
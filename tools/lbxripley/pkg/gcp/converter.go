@@ -0,0 +1,99 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
+)
+
+// LogEntry is a Cloud Logging entry of an external HTTP(S) load balancer,
+// limited to the fields needed to replay requests
+type LogEntry struct {
+	Timestamp   string      `json:"timestamp"`
+	HTTPRequest HTTPRequest `json:"httpRequest"`
+}
+
+type HTTPRequest struct {
+	RequestMethod string `json:"requestMethod"`
+	RequestURL    string `json:"requestUrl"`
+	UserAgent     string `json:"userAgent"`
+	Referer       string `json:"referer"`
+	Status        int    `json:"status"`
+	Latency       string `json:"latency"`
+	Protocol      string `json:"protocol"`
+}
+
+// Converter converts GCP HTTP(S) load balancer log entries exported as JSON
+type Converter struct{}
+
+var _ convert.Converter = (*Converter)(nil)
+
+func New() *Converter {
+	return &Converter{}
+}
+
+func (c *Converter) Convert(line []byte, options convert.Options) (*ripley.Request, error) {
+	var entry LogEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse line: %w", err)
+	}
+
+	return c.ConvertToRipley(entry, options)
+}
+
+func (c *Converter) ConvertToRipley(entry LogEntry, options convert.Options) (*ripley.Request, error) {
+	timestamp, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp %s: %w", entry.Timestamp, err)
+	}
+
+	if entry.HTTPRequest.RequestMethod == "" || entry.HTTPRequest.RequestURL == "" {
+		return nil, fmt.Errorf("missing httpRequest.requestMethod or httpRequest.requestUrl")
+	}
+
+	targetURL, err := options.RewriteURL(entry.HTTPRequest.RequestURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build target URL: %w", err)
+	}
+
+	latency, err := c.parseLatency(entry.HTTPRequest.Latency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse latency %s: %w", entry.HTTPRequest.Latency, err)
+	}
+
+	headers := make(ripley.Headers)
+
+	if entry.HTTPRequest.UserAgent != "" {
+		headers["User-Agent"] = []string{entry.HTTPRequest.UserAgent}
+	}
+
+	if entry.HTTPRequest.Referer != "" {
+		headers["Referer"] = []string{entry.HTTPRequest.Referer}
+	}
+
+	return &ripley.Request{
+		Method:          entry.HTTPRequest.RequestMethod,
+		Url:             targetURL,
+		Timestamp:       timestamp,
+		Headers:         headers,
+		ExpectedStatus:  entry.HTTPRequest.Status,
+		OriginalLatency: latency,
+	}, nil
+}
+
+// parseLatency parses latencies in the Duration JSON format, e.g. "0.035s"
+func (c *Converter) parseLatency(latency string) (time.Duration, error) {
+	if latency == "" {
+		return 0, nil
+	}
+
+	if !strings.HasSuffix(latency, "s") {
+		return 0, fmt.Errorf("missing seconds suffix")
+	}
+
+	return time.ParseDuration(latency)
+}
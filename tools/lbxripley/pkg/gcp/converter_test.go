@@ -0,0 +1,77 @@
+package gcp
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
+)
+
+func TestConverter_Convert(t *testing.T) {
+	conv := New()
+
+	line := `{"httpRequest":{"requestMethod":"GET","requestUrl":"http://www.test.com/api/v1/data?id=12345","status":200,"userAgent":"TestClient/1.0","referer":"https://www.test.com/","latency":"0.035s"},"resource":{"type":"http_load_balancer"},"timestamp":"2025-09-03T15:30:32.928995Z"}`
+
+	tests := []struct {
+		name     string
+		options  convert.Options
+		expected string
+	}{
+		{name: "no host change", expected: "http://www.test.com/api/v1/data?id=12345"},
+		{name: "host change", options: convert.Options{Host: "localhost:8080"}, expected: "http://localhost:8080/api/v1/data?id=12345"},
+		{name: "host change and HTTPS upgrade", options: convert.Options{Host: "localhost:8443", HTTPS: true}, expected: "https://localhost:8443/api/v1/data?id=12345"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := conv.Convert([]byte(line), tt.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result.Method != "GET" {
+				t.Errorf("Method: got %s, want GET", result.Method)
+			}
+
+			if result.Url != tt.expected {
+				t.Errorf("URL: got %s, want %s", result.Url, tt.expected)
+			}
+
+			if want := time.Date(2025, 9, 3, 15, 30, 32, 928995000, time.UTC); !result.Timestamp.Equal(want) {
+				t.Errorf("Timestamp: got %v, want %v", result.Timestamp, want)
+			}
+
+			if result.ExpectedStatus != 200 {
+				t.Errorf("ExpectedStatus: got %d, want 200", result.ExpectedStatus)
+			}
+
+			if result.OriginalLatency != 35*time.Millisecond {
+				t.Errorf("OriginalLatency: got %s, want 35ms", result.OriginalLatency)
+			}
+
+			want := ripley.Headers{"User-Agent": {"TestClient/1.0"}, "Referer": {"https://www.test.com/"}}
+			if len(result.Headers) != len(want) || !slices.Equal(result.Headers["User-Agent"], want["User-Agent"]) || !slices.Equal(result.Headers["Referer"], want["Referer"]) {
+				t.Errorf("Headers: got %v, want %v", result.Headers, want)
+			}
+		})
+	}
+}
+
+func TestConverter_ConvertErrors(t *testing.T) {
+	conv := New()
+
+	lines := []string{
+		`not json`,
+		`{"httpRequest":{"requestMethod":"GET","requestUrl":"http://a/"},"timestamp":"yesterday"}`,
+		`{"jsonPayload":{"message":"not a request"},"timestamp":"2025-09-03T15:30:32Z"}`,
+		`{"httpRequest":{"requestMethod":"GET","requestUrl":"http://a/","latency":"fast"},"timestamp":"2025-09-03T15:30:32Z"}`,
+	}
+
+	for _, line := range lines {
+		if _, err := conv.Convert([]byte(line), convert.Options{}); err == nil {
+			t.Errorf("expected error for line %s but got none", line)
+		}
+	}
+}
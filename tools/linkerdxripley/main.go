@@ -1,24 +1,21 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"strings"
 
+	"github.com/loveholidays/ripley/pkg/convert"
 	"github.com/loveholidays/ripley/tools/linkerdxripley/pkg/converter"
-	"github.com/loveholidays/ripley/tools/linkerdxripley/pkg/linkerd"
 )
 
 func main() {
-	var (
-		newHost = flag.String("host", "", "New host to replace the original host in URLs")
-		https   = flag.Bool("https", false, "Upgrade HTTP requests to HTTPS")
-		help    = flag.Bool("help", false, "Show usage information")
-	)
+	var options convert.Options
+	options.RegisterFlags(flag.CommandLine)
+	preserveHost := flag.Bool("preserve-host", false, "Send the original host in the Host header, for virtual host routing after host rewrites")
+	traceHeader := flag.String("trace-header", "", "Header to send the original trace ID in, e.g. X-B3-TraceId")
+	help := flag.Bool("help", false, "Show usage information")
 	flag.Parse()
 
 	if *help {
@@ -28,41 +25,21 @@ func main() {
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  cat linkerd.jsonl | linkerdxripley -host localhost:8080 > ripley.jsonl\n")
-		fmt.Fprintf(os.Stderr, "  cat linkerd.jsonl | linkerdxripley -host localhost:8080 -https > ripley.jsonl\n\n")
+		fmt.Fprintf(os.Stderr, "  cat linkerd.jsonl | linkerdxripley -host localhost:8080 -https > ripley.jsonl\n")
+		fmt.Fprintf(os.Stderr, "  cat linkerd.jsonl | linkerdxripley -host ingress:8080 -preserve-host -trace-header X-B3-TraceId > ripley.jsonl\n\n")
 		return
 	}
 
-	conv := converter.New()
-	scanner := bufio.NewScanner(os.Stdin)
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetEscapeHTML(false)
+	stats, err := convert.Run(os.Stdin, os.Stdout, converter.NewWithOptions(converter.Options{
+		PreserveHost: *preserveHost,
+		TraceHeader:  *traceHeader,
+	}), options)
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		var linkerdReq linkerd.Request
-		if err := json.Unmarshal([]byte(line), &linkerdReq); err != nil {
-			log.Printf("failed to parse line: %s, error: %v", line, err)
-			continue
-		}
-
-		ripleyReq, err := conv.ConvertToRipley(linkerdReq, *newHost, *https)
-		if err != nil {
-			log.Printf("failed to convert request: %v", err)
-			continue
-		}
-
-		if err := encoder.Encode(ripleyReq); err != nil {
-			log.Printf("failed to encode ripley request: %v", err)
-			continue
-		}
+	if reportErr := stats.Report(os.Stderr); reportErr != nil {
+		log.Printf("failed to write report: %v", reportErr)
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("error reading input: %v", err)
+	if err != nil {
+		log.Fatalf("error converting input: %v", err)
 	}
 }
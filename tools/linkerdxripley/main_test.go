@@ -20,6 +20,10 @@ func TestCLIIntegration(t *testing.T) {
 	t.Run("multiple lines processing", func(t *testing.T) {
 		testMultipleLineProcessing(t)
 	})
+
+	t.Run("preserved linkerd fields", func(t *testing.T) {
+		testPreservedFields(t)
+	})
 }
 
 func testBasicConversion(t *testing.T) {
@@ -40,6 +44,28 @@ func testHostChangeConversion(t *testing.T) {
 	assertFieldEquals(t, result, "url", "http://localhost:8080/api/v1/data?id=12345")
 }
 
+func testPreservedFields(t *testing.T) {
+	linkerdData := getSampleLinkerdData()[0]
+	output := runCLICommand(t, []string{"-host", "localhost:8080", "-preserve-host", "-trace-header", "X-B3-TraceId"}, linkerdData)
+
+	result := parseJSONOutput(t, output)
+	headers, ok := result["headers"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected headers object, got %v", result["headers"])
+	}
+
+	assertFieldEquals(t, headers, "Host", "api-service.test.svc.cluster.local")
+	assertFieldEquals(t, headers, "X-B3-TraceId", "abc123")
+
+	if result["expectedStatus"] != float64(200) {
+		t.Errorf("expected expectedStatus 200, got %v", result["expectedStatus"])
+	}
+
+	if result["originalLatency"] != float64(2500000) {
+		t.Errorf("expected originalLatency 2500000, got %v", result["originalLatency"])
+	}
+}
+
 func testMultipleLineProcessing(t *testing.T) {
 	linkerdLines := getSampleLinkerdData()
 	linkerdData := strings.Join(linkerdLines, "\n")
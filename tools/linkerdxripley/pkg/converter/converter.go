@@ -1,38 +1,74 @@
 package converter
 
 import (
+	"encoding/json"
 	"fmt"
-	"net/url"
+	"strconv"
 	"time"
 
 	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
 	"github.com/loveholidays/ripley/tools/linkerdxripley/pkg/linkerd"
 )
 
-type Converter struct{}
+// Options select which Linkerd fields are carried over besides the request itself
+type Options struct {
+	// Send the original host in the Host header, so virtual host routing
+	// still works when the URL host is rewritten
+	PreserveHost bool
+	// Header the trace ID is sent in, the trace ID is dropped when empty
+	TraceHeader string
+}
+
+type Converter struct {
+	options Options
+}
+
+var _ convert.Converter = (*Converter)(nil)
 
 func New() *Converter {
 	return &Converter{}
 }
 
+func NewWithOptions(options Options) *Converter {
+	return &Converter{options: options}
+}
+
+// Convert converts a Linkerd JSON access log line
+func (c *Converter) Convert(line []byte, options convert.Options) (*ripley.Request, error) {
+	var linkerdReq linkerd.Request
+	if err := json.Unmarshal(line, &linkerdReq); err != nil {
+		return nil, fmt.Errorf("failed to parse line: %w", err)
+	}
+
+	return c.convertRequest(linkerdReq, options)
+}
+
 func (c *Converter) ConvertToRipley(linkerd linkerd.Request, newHost string, upgradeHTTPS bool) (*ripley.Request, error) {
+	return c.convertRequest(linkerd, convert.Options{Host: newHost, HTTPS: upgradeHTTPS})
+}
+
+func (c *Converter) convertRequest(linkerd linkerd.Request, options convert.Options) (*ripley.Request, error) {
 	timestamp, err := c.parseTimestamp(linkerd.Timestamp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse timestamp %s: %w", linkerd.Timestamp, err)
 	}
 
-	targetURL, err := c.buildTargetURL(linkerd.URI, newHost, upgradeHTTPS)
+	targetURL, err := options.RewriteURL(linkerd.URI)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build target URL: %w", err)
 	}
 
+	latency := c.parseLatency(linkerd.TotalNs)
 	headers := c.buildHeaders(linkerd)
 
 	ripleyReq := &ripley.Request{
-		Method:    linkerd.Method,
-		Url:       targetURL,
-		Timestamp: timestamp,
-		Headers:   headers,
+		Method:          linkerd.Method,
+		Url:             targetURL,
+		Timestamp:       timestamp,
+		Headers:         headers,
+		ExpectedStatus:  linkerd.Status,
+		OriginalLatency: latency,
 	}
 
 	return ripleyReq, nil
@@ -42,28 +78,30 @@ func (c *Converter) parseTimestamp(timestampStr string) (time.Time, error) {
 	return time.Parse(time.RFC3339Nano, timestampStr)
 }
 
-func (c *Converter) buildTargetURL(originalURI, newHost string, upgradeHTTPS bool) (string, error) {
-	parsedURL, err := url.Parse(originalURI)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse URI %s: %w", originalURI, err)
-	}
-
-	if newHost != "" {
-		parsedURL.Host = newHost
-	}
-
-	if upgradeHTTPS && parsedURL.Scheme == "http" {
-		parsedURL.Scheme = "https"
+// parseLatency leaves the latency empty when total_ns is missing or
+// malformed, as the request can still be replayed without it
+func (c *Converter) parseLatency(totalNs string) time.Duration {
+	ns, err := strconv.ParseInt(totalNs, 10, 64)
+	if err != nil || ns < 0 {
+		return 0
 	}
 
-	return parsedURL.String(), nil
+	return time.Duration(ns)
 }
 
-func (c *Converter) buildHeaders(linkerd linkerd.Request) map[string]string {
-	headers := make(map[string]string)
+func (c *Converter) buildHeaders(linkerd linkerd.Request) ripley.Headers {
+	headers := make(ripley.Headers)
 
 	if linkerd.UserAgent != "" {
-		headers["User-Agent"] = linkerd.UserAgent
+		headers["User-Agent"] = []string{linkerd.UserAgent}
+	}
+
+	if c.options.PreserveHost && linkerd.Host != "" {
+		headers["Host"] = []string{linkerd.Host}
+	}
+
+	if c.options.TraceHeader != "" && linkerd.TraceID != "" {
+		headers[c.options.TraceHeader] = []string{linkerd.TraceID}
 	}
 
 	return headers
@@ -2,10 +2,12 @@ package converter
 
 import (
 	"encoding/json"
+	"slices"
 	"testing"
 	"time"
 
 	ripley "github.com/loveholidays/ripley/pkg"
+	"github.com/loveholidays/ripley/pkg/convert"
 	"github.com/loveholidays/ripley/tools/linkerdxripley/pkg/linkerd"
 )
 
@@ -41,8 +43,8 @@ func testBasicConversions(t *testing.T) {
 				Method:    "GET",
 				Url:       "http://api-service.test.svc.cluster.local/api/v1/data?id=12345",
 				Timestamp: mustParseTime("2025-09-03T15:30:32.928995068Z"),
-				Headers: map[string]string{
-					"User-Agent": "TestClient/1.0",
+				Headers: ripley.Headers{
+					"User-Agent": {"TestClient/1.0"},
 				},
 			},
 		},
@@ -54,8 +56,8 @@ func testBasicConversions(t *testing.T) {
 				Method:    "POST",
 				Url:       "http://localhost:8080/endpoint",
 				Timestamp: mustParseTime("2025-09-03T15:30:32.928995068Z"),
-				Headers: map[string]string{
-					"User-Agent": "TestAgent/1.0",
+				Headers: ripley.Headers{
+					"User-Agent": {"TestAgent/1.0"},
 				},
 			},
 		},
@@ -67,7 +69,7 @@ func testBasicConversions(t *testing.T) {
 				Method:    "GET",
 				Url:       "http://api.test.com/endpoint",
 				Timestamp: mustParseTime("2025-09-03T15:30:32.928995068Z"),
-				Headers:   map[string]string{},
+				Headers:   ripley.Headers{},
 			},
 		},
 		{
@@ -78,8 +80,8 @@ func testBasicConversions(t *testing.T) {
 				Method:    "GET",
 				Url:       "http://staging.search-api.test.com:9000/search?q=test&category=books&page=1&limit=10&sort=price",
 				Timestamp: mustParseTime("2025-09-03T15:30:32.928995068Z"),
-				Headers: map[string]string{
-					"User-Agent": "TestBrowser/2.0",
+				Headers: ripley.Headers{
+					"User-Agent": {"TestBrowser/2.0"},
 				},
 			},
 		},
@@ -101,8 +103,8 @@ func testHTTPSUpgradeConversions(t *testing.T) {
 				Method:    "GET",
 				Url:       "https://api.test.com/secure-endpoint",
 				Timestamp: mustParseTime("2025-09-03T15:30:32.928995068Z"),
-				Headers: map[string]string{
-					"User-Agent": "TestAgent/1.0",
+				Headers: ripley.Headers{
+					"User-Agent": {"TestAgent/1.0"},
 				},
 			},
 		},
@@ -115,8 +117,8 @@ func testHTTPSUpgradeConversions(t *testing.T) {
 				Method:    "GET",
 				Url:       "https://localhost:8443/secure-endpoint",
 				Timestamp: mustParseTime("2025-09-03T15:30:32.928995068Z"),
-				Headers: map[string]string{
-					"User-Agent": "TestAgent/1.0",
+				Headers: ripley.Headers{
+					"User-Agent": {"TestAgent/1.0"},
 				},
 			},
 		},
@@ -129,8 +131,8 @@ func testHTTPSUpgradeConversions(t *testing.T) {
 				Method:    "GET",
 				Url:       "https://localhost:8443/endpoint",
 				Timestamp: mustParseTime("2025-09-03T15:30:32.928995068Z"),
-				Headers: map[string]string{
-					"User-Agent": "TestAgent/1.0",
+				Headers: ripley.Headers{
+					"User-Agent": {"TestAgent/1.0"},
 				},
 			},
 		},
@@ -249,15 +251,15 @@ func assertConversionResult(t *testing.T, result, expected *ripley.Request) {
 	assertHeadersMatch(t, result.Headers, expected.Headers)
 }
 
-func assertHeadersMatch(t *testing.T, result, expected map[string]string) {
+func assertHeadersMatch(t *testing.T, result, expected ripley.Headers) {
 	if len(result) != len(expected) {
 		t.Errorf("Headers length: got %d, want %d", len(result), len(expected))
 		return
 	}
 
 	for k, v := range expected {
-		if result[k] != v {
-			t.Errorf("Header %s: got %s, want %s", k, result[k], v)
+		if !slices.Equal(result[k], v) {
+			t.Errorf("Header %s: got %v, want %v", k, result[k], v)
 		}
 	}
 }
@@ -289,7 +291,7 @@ func TestConverter_parseTimestamp(t *testing.T) {
 	}
 }
 
-func TestConverter_buildTargetURL(t *testing.T) {
+func TestConverter_ConvertTargetURL(t *testing.T) {
 	conv := New()
 
 	tests := []struct {
@@ -324,7 +326,12 @@ func TestConverter_buildTargetURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := conv.buildTargetURL(tt.originalURI, tt.newHost, false)
+			line, err := json.Marshal(linkerd.Request{Method: "GET", URI: tt.originalURI, Timestamp: "2025-09-03T15:30:32Z"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			result, err := conv.Convert(line, convert.Options{Host: tt.newHost})
 
 			if tt.expectError {
 				if err == nil {
@@ -338,8 +345,8 @@ func TestConverter_buildTargetURL(t *testing.T) {
 				return
 			}
 
-			if result != tt.expected {
-				t.Errorf("got %s, want %s", result, tt.expected)
+			if result.Url != tt.expected {
+				t.Errorf("got %s, want %s", result.Url, tt.expected)
 			}
 		})
 	}
@@ -351,7 +358,7 @@ func TestConverter_buildHeaders(t *testing.T) {
 	tests := []struct {
 		name     string
 		linkerd  linkerd.Request
-		expected map[string]string
+		expected ripley.Headers
 	}{
 		{
 			name: "user agent present",
@@ -359,8 +366,8 @@ func TestConverter_buildHeaders(t *testing.T) {
 				UserAgent: "TestAgent/1.0",
 				Host:      "test.com",
 			},
-			expected: map[string]string{
-				"User-Agent": "TestAgent/1.0",
+			expected: ripley.Headers{
+				"User-Agent": {"TestAgent/1.0"},
 			},
 		},
 		{
@@ -369,8 +376,8 @@ func TestConverter_buildHeaders(t *testing.T) {
 				UserAgent: "TestAgent/1.0",
 				Host:      "",
 			},
-			expected: map[string]string{
-				"User-Agent": "TestAgent/1.0",
+			expected: ripley.Headers{
+				"User-Agent": {"TestAgent/1.0"},
 			},
 		},
 		{
@@ -379,7 +386,7 @@ func TestConverter_buildHeaders(t *testing.T) {
 				UserAgent: "",
 				Host:      "test.com",
 			},
-			expected: map[string]string{},
+			expected: ripley.Headers{},
 		},
 		{
 			name: "no headers",
@@ -387,7 +394,43 @@ func TestConverter_buildHeaders(t *testing.T) {
 				UserAgent: "",
 				Host:      "",
 			},
-			expected: map[string]string{},
+			expected: ripley.Headers{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := conv.buildHeaders(tt.linkerd)
+			assertHeadersMatch(t, result, tt.expected)
+		})
+	}
+}
+
+func TestConverter_buildHeadersWithOptions(t *testing.T) {
+	conv := NewWithOptions(Options{PreserveHost: true, TraceHeader: "X-B3-TraceId"})
+
+	tests := []struct {
+		name     string
+		linkerd  linkerd.Request
+		expected ripley.Headers
+	}{
+		{
+			name: "host and trace ID",
+			linkerd: linkerd.Request{
+				UserAgent: "TestAgent/1.0",
+				Host:      "test.com",
+				TraceID:   "abc123",
+			},
+			expected: ripley.Headers{
+				"User-Agent":   {"TestAgent/1.0"},
+				"Host":         {"test.com"},
+				"X-B3-TraceId": {"abc123"},
+			},
+		},
+		{
+			name:     "no host or trace ID",
+			linkerd:  linkerd.Request{},
+			expected: ripley.Headers{},
 		},
 	}
 
@@ -399,6 +442,38 @@ func TestConverter_buildHeaders(t *testing.T) {
 	}
 }
 
+func TestConverter_originalResponse(t *testing.T) {
+	conv := New()
+
+	result, err := conv.ConvertToRipley(createFullLinkerdRequest(), "localhost:8080", false)
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+
+	if result.ExpectedStatus != 200 {
+		t.Errorf("ExpectedStatus: got %d, want 200", result.ExpectedStatus)
+	}
+
+	if result.OriginalLatency != 2500*time.Microsecond {
+		t.Errorf("OriginalLatency: got %v, want 2.5ms", result.OriginalLatency)
+	}
+
+	for _, totalNs := range []string{"", "fast", "-1"} {
+		request := createFullLinkerdRequest()
+		request.TotalNs = totalNs
+
+		result, err := conv.ConvertToRipley(request, "", false)
+		if err != nil {
+			t.Errorf("ConvertToRipley with total_ns %q err = %v; want nil", totalNs, err)
+			continue
+		}
+
+		if result.OriginalLatency != 0 {
+			t.Errorf("OriginalLatency with total_ns %q = %v; want 0", totalNs, result.OriginalLatency)
+		}
+	}
+}
+
 func TestJSONSerialization(t *testing.T) {
 	conv := New()
 
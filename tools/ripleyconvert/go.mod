@@ -0,0 +1,36 @@
+module github.com/loveholidays/ripley/tools/ripleyconvert
+
+go 1.23.0
+
+toolchain go1.24.1
+
+require (
+	github.com/loveholidays/ripley v0.0.0
+	github.com/loveholidays/ripley/tools/accesslogxripley v0.0.0
+	github.com/loveholidays/ripley/tools/envoyxripley v0.0.0
+	github.com/loveholidays/ripley/tools/harxripley v0.0.0
+	github.com/loveholidays/ripley/tools/lbxripley v0.0.0
+	github.com/loveholidays/ripley/tools/linkerdxripley v0.0.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_golang v1.23.2 // indirect
+	github.com/prometheus/client_model v0.6.2 // indirect
+	github.com/prometheus/common v0.66.1 // indirect
+	github.com/prometheus/procfs v0.16.1 // indirect
+	go.yaml.in/yaml/v2 v2.4.2 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	google.golang.org/protobuf v1.36.8 // indirect
+)
+
+replace (
+	github.com/loveholidays/ripley => ../..
+	github.com/loveholidays/ripley/tools/accesslogxripley => ../accesslogxripley
+	github.com/loveholidays/ripley/tools/envoyxripley => ../envoyxripley
+	github.com/loveholidays/ripley/tools/harxripley => ../harxripley
+	github.com/loveholidays/ripley/tools/lbxripley => ../lbxripley
+	github.com/loveholidays/ripley/tools/linkerdxripley => ../linkerdxripley
+)
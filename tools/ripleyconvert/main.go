@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/loveholidays/ripley/pkg/convert"
+	"github.com/loveholidays/ripley/tools/accesslogxripley/pkg/accesslog"
+	accesslogconverter "github.com/loveholidays/ripley/tools/accesslogxripley/pkg/converter"
+	envoyconverter "github.com/loveholidays/ripley/tools/envoyxripley/pkg/converter"
+	harconverter "github.com/loveholidays/ripley/tools/harxripley/pkg/converter"
+	"github.com/loveholidays/ripley/tools/lbxripley/pkg/alb"
+	"github.com/loveholidays/ripley/tools/lbxripley/pkg/cloudfront"
+	"github.com/loveholidays/ripley/tools/lbxripley/pkg/gcp"
+	linkerdconverter "github.com/loveholidays/ripley/tools/linkerdxripley/pkg/converter"
+)
+
+const formats = "linkerd, har, nginx, apache, envoy, alb, cloudfront or gcp"
+
+func main() {
+	var options convert.Options
+	options.RegisterFlags(flag.CommandLine)
+	format := flag.String("format", "", "Input format, "+formats)
+	logFormat := flag.String("log-format", "combined", `nginx and apache log format, "combined", "common" or an nginx log_format template`)
+	var linkerdOptions linkerdconverter.Options
+	flag.BoolVar(&linkerdOptions.PreserveHost, "preserve-host", false, "linkerd: send the original host in the Host header")
+	flag.StringVar(&linkerdOptions.TraceHeader, "trace-header", "", "linkerd: header to send the original trace ID in, e.g. X-B3-TraceId")
+	help := flag.Bool("help", false, "Show usage information")
+	flag.Parse()
+
+	if *help {
+		fmt.Fprintf(os.Stderr, "ripleyconvert - Convert logs of many formats to Ripley format\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: ripleyconvert -format FORMAT [options] < input > output.jsonl\n\n")
+		fmt.Fprintf(os.Stderr, "Formats: %s\n\n", formats)
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  cat linkerd.jsonl | ripleyconvert -format linkerd -host localhost:8080 > ripley.jsonl\n")
+		fmt.Fprintf(os.Stderr, "  cat access.log | ripleyconvert -format nginx -host-map api.example.com=localhost:8080 > ripley.jsonl\n\n")
+		return
+	}
+
+	conv, err := newConverter(*format, *logFormat, linkerdOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stats, err := convert.Run(os.Stdin, os.Stdout, conv, options)
+
+	if reportErr := stats.Report(os.Stderr); reportErr != nil {
+		log.Printf("failed to write report: %v", reportErr)
+	}
+
+	if err != nil {
+		log.Fatalf("error converting input: %v", err)
+	}
+}
+
+func newConverter(format, logFormat string, linkerdOptions linkerdconverter.Options) (convert.Converter, error) {
+	switch format {
+	case "linkerd":
+		return linkerdconverter.NewWithOptions(linkerdOptions), nil
+	case "har":
+		return harconverter.New(), nil
+	case "nginx", "apache":
+		parser, err := accesslog.NewParser(logFormat)
+		if err != nil {
+			return nil, err
+		}
+		return accesslogconverter.NewWithParser(parser), nil
+	case "envoy":
+		return envoyconverter.New(), nil
+	case "alb":
+		return alb.New(), nil
+	case "cloudfront":
+		return cloudfront.New(), nil
+	case "gcp":
+		return gcp.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown format: %q, use %s", format, formats)
+	}
+}
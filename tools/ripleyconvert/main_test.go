@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCLIFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		file   string
+	}{
+		{"linkerd", "testdata/linkerd.jsonl"},
+		{"har", "testdata/sample.har"},
+		{"nginx", "testdata/access.log"},
+		{"envoy", "testdata/envoy.log"},
+		{"alb", "testdata/alb.log"},
+		{"cloudfront", "testdata/cloudfront.log"},
+		{"gcp", "testdata/gcp.jsonl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			input, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", tt.file, err)
+			}
+
+			stdout, stderr := runCLICommand(t, []string{"-format", tt.format, "-host", "localhost:8080"}, string(input))
+
+			lines := strings.Split(strings.TrimSpace(stdout), "\n")
+			for _, line := range lines {
+				result := parseJSONOutput(t, line)
+				targetURL, err := url.Parse(result["url"].(string))
+				if err != nil {
+					t.Fatalf("failed to parse url %v: %v", result["url"], err)
+				}
+
+				if targetURL.Host != "localhost:8080" {
+					t.Errorf("expected host localhost:8080, got %s", targetURL.Host)
+				}
+			}
+
+			if !strings.Contains(stderr, "0 failed") {
+				t.Errorf("expected no failures, got report %q", stderr)
+			}
+		})
+	}
+}
+
+func TestCLIHostMapAndReport(t *testing.T) {
+	input := `192.168.1.100 - - [03/Sep/2025:15:30:32 +0000] "GET /api/v1/data?id=12345 HTTP/1.1" 200 512 "-" "TestClient/1.0"
+not an access log line
+`
+	stdout, stderr := runCLICommand(t, []string{"-format", "nginx", "-log-format", "common", "-host", "localhost:8080", "-https"}, input)
+
+	assertFieldEquals(t, parseJSONOutput(t, stdout), "url", "https://localhost:8080/api/v1/data?id=12345")
+
+	if !strings.Contains(stderr, "converted 1 of 2 records, 1 failed, 0 skipped") {
+		t.Errorf("expected report of 1 failure, got %q", stderr)
+	}
+
+	if !strings.Contains(stderr, "record 2:") {
+		t.Errorf("expected failure of record 2 to be reported, got %q", stderr)
+	}
+}
+
+func TestCLIUnknownFormat(t *testing.T) {
+	cmd := exec.Command("go", "run", "main.go", "-format", "iis")
+	cmd.Stdin = strings.NewReader("")
+
+	if err := cmd.Run(); err == nil {
+		t.Errorf("expected unknown format to fail")
+	}
+}
+
+func runCLICommand(t *testing.T, args []string, input string) (string, string) {
+	cmdArgs := append([]string{"run", "main.go"}, args...)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Dir = "."
+	cmd.Stdin = strings.NewReader(input)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nstderr: %s", err, stderr.String())
+	}
+
+	return stdout.String(), stderr.String()
+}
+
+func parseJSONOutput(t *testing.T, output string) map[string]interface{} {
+	var result map[string]interface{}
+	err := json.Unmarshal([]byte(strings.TrimSpace(output)), &result)
+	if err != nil {
+		t.Fatalf("failed to parse output JSON: %v", err)
+	}
+	return result
+}
+
+func assertFieldEquals(t *testing.T, result map[string]interface{}, field, expected string) {
+	if result[field] != expected {
+		t.Errorf("expected %s %s, got %v", field, expected, result[field])
+	}
+}